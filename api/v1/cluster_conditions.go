@@ -0,0 +1,49 @@
+package v1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetCondition adds or replaces the condition of the given type on the Cluster, the
+// same way meta.SetStatusCondition does: LastTransitionTime only moves when the
+// status of the condition changes. reason must be set, the CRD rejects empty reasons.
+func (c *Cluster) SetCondition(conditionType ClusterConditionType, status v1.ConditionStatus, reason, message string) {
+	condition := ClusterCondition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: c.Generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+
+	for i, existing := range c.Status.Conditions {
+		if existing.Type != conditionType {
+			continue
+		}
+		if existing.Status == status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		c.Status.Conditions[i] = condition
+		return
+	}
+
+	c.Status.Conditions = append(c.Status.Conditions, condition)
+}
+
+// GetCondition returns the condition of the given type, or nil if it hasn't been set
+func (c Cluster) GetCondition(conditionType ClusterConditionType) *ClusterCondition {
+	for i := range c.Status.Conditions {
+		if c.Status.Conditions[i].Type == conditionType {
+			return &c.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue returns whether the condition of the given type is set and True
+func (c Cluster) IsConditionTrue(conditionType ClusterConditionType) bool {
+	condition := c.GetCondition(conditionType)
+	return condition != nil && condition.Status == v1.ConditionTrue
+}
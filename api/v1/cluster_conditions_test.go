@@ -0,0 +1,132 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetCondition(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+
+	tests := []struct {
+		name           string
+		existing       []ClusterCondition
+		status         v1.ConditionStatus
+		wantConditions int
+		wantMoved      bool
+	}{
+		{
+			name:           "new condition",
+			status:         v1.ConditionTrue,
+			wantConditions: 1,
+			wantMoved:      true,
+		},
+		{
+			name:           "same status",
+			existing:       []ClusterCondition{{Type: ClusterReady, Status: v1.ConditionTrue, LastTransitionTime: earlier}},
+			status:         v1.ConditionTrue,
+			wantConditions: 1,
+		},
+		{
+			name:           "status changed",
+			existing:       []ClusterCondition{{Type: ClusterReady, Status: v1.ConditionFalse, LastTransitionTime: earlier}},
+			status:         v1.ConditionTrue,
+			wantConditions: 1,
+			wantMoved:      true,
+		},
+		{
+			name:           "other conditions are kept",
+			existing:       []ClusterCondition{{Type: ClusterPodScheduled, Status: v1.ConditionTrue, LastTransitionTime: earlier}},
+			status:         v1.ConditionFalse,
+			wantConditions: 2,
+			wantMoved:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Generation = 3
+			c.Status.Conditions = tt.existing
+
+			c.SetCondition(ClusterReady, tt.status, "Reason", "message")
+
+			if len(c.Status.Conditions) != tt.wantConditions {
+				t.Fatalf("SetCondition() left %d conditions, want %d", len(c.Status.Conditions), tt.wantConditions)
+			}
+			got := c.GetCondition(ClusterReady)
+			if got == nil {
+				t.Fatalf("GetCondition() = nil after SetCondition()")
+			}
+			if got.Status != tt.status || got.Reason != "Reason" || got.Message != "message" || got.ObservedGeneration != 3 {
+				t.Errorf("SetCondition() = %+v, want status %s, reason, message and generation 3", got, tt.status)
+			}
+			if moved := !got.LastTransitionTime.Equal(&earlier); moved != tt.wantMoved {
+				t.Errorf("LastTransitionTime moved = %v, want %v", moved, tt.wantMoved)
+			}
+		})
+	}
+}
+
+func TestIsConditionTrue(t *testing.T) {
+	c := Cluster{}
+	c.Status.Conditions = []ClusterCondition{
+		{Type: ClusterPodScheduled, Status: v1.ConditionTrue},
+		{Type: ClusterBootstrapped, Status: v1.ConditionFalse},
+		{Type: ClusterManifestsApplied, Status: v1.ConditionUnknown},
+	}
+
+	tests := []struct {
+		conditionType ClusterConditionType
+		want          bool
+	}{
+		{conditionType: ClusterPodScheduled, want: true},
+		{conditionType: ClusterBootstrapped},
+		{conditionType: ClusterManifestsApplied},
+		{conditionType: ClusterReady},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.conditionType), func(t *testing.T) {
+			if got := c.IsConditionTrue(tt.conditionType); got != tt.want {
+				t.Errorf("IsConditionTrue(%s) = %v, want %v", tt.conditionType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterConditionRequiredFields(t *testing.T) {
+	c := Cluster{}
+	c.SetCondition(ClusterKubeconfigReady, v1.ConditionTrue, "KubeconfigStored", "")
+
+	data, err := json.Marshal(c.Status.Conditions[0])
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, field := range []string{`"type":`, `"status":`, `"lastTransitionTime":`, `"reason":`, `"message":`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("json.Marshal() = %s, missing required %s", data, field)
+		}
+	}
+}
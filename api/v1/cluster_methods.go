@@ -2,57 +2,282 @@ package v1
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
 	"reflect"
 	"strings"
+	"time"
 
-	yaml "gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+)
 
-	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+const (
+	// progressDir is where the bootstrap command drops marker files inside the pod
+	progressDir = "/tmp/kaas"
+	// bootstrappedMarker is written once the nested cluster has been created
+	bootstrappedMarker = "bootstrapped"
+	// manifestsAppliedMarker is written once every clusterYAML manifest has been applied
+	manifestsAppliedMarker = "manifests-applied"
+	// waitForRolloutMarker is written when the readiness probe should also wait for clusterYAML workloads to roll out
+	waitForRolloutMarker = "wait-for-rollout"
+	// logsDir is where nested cluster logs are exported to inside the pod
+	logsDir = progressDir + "/logs"
+	// teardownStartedMarker is written once a background teardown has been started
+	teardownStartedMarker = "teardown-started"
+	// tornDownMarker holds the exit code of the background teardown once it has finished
+	tornDownMarker = "torn-down"
+	// teardownLog is where the background teardown writes its output
+	teardownLog = progressDir + "/teardown.log"
+	// teardownLogsBundle is where the background teardown leaves the logs it exported, base64 encoded
+	teardownLogsBundle = progressDir + "/teardown-logs"
+	// logsExportedMarker holds the exit code of a background log export once it has finished
+	logsExportedMarker = "logs-exported"
+	// exportedLogsBundle is where a background log export leaves the logs, base64 encoded
+	exportedLogsBundle = progressDir + "/exported-logs"
 )
 
+// DefaultReadinessCheck is what the Cluster pod's readiness probe runs. The pod is only ready once
+// bootstrapping has finished, the nested API server passes /readyz and every node is Ready.
+// If asked to, every Deployment, DaemonSet and StatefulSet in clusterYAML must have rolled out too.
+var DefaultReadinessCheck = readinessCheck(true)
+
+// readinessCheck builds DefaultReadinessCheck, leaving out the node check for clusters without nodes
+func readinessCheck(waitForNodes bool) string {
+	steps := []string{
+		"set -eo pipefail",
+		fmt.Sprintf("test -f %s/%s", progressDir, manifestsAppliedMarker),
+		"kubectl get --raw /readyz >/dev/null",
+	}
+	if waitForNodes {
+		steps = append(steps, "kubectl wait --for=condition=Ready nodes --all --timeout=5s >/dev/null")
+	}
+	steps = append(steps,
+		fmt.Sprintf("if [ -f %s/%s ]; then", progressDir, waitForRolloutMarker),
+		"  for f in /honk/[0-9]*.yaml; do kubectl get -f \"$f\" --no-headers -o custom-columns=KIND:.kind,NAMESPACE:.metadata.namespace,NAME:.metadata.name || exit 1; done | while read kind namespace name; do",
+		"    case \"$kind\" in Deployment|DaemonSet|StatefulSet)",
+		"      [ \"$namespace\" = \"<none>\" ] && namespace=default",
+		"      kubectl -n \"$namespace\" rollout status \"$kind/$name\" --timeout=5s >/dev/null || exit 1;;",
+		"    esac",
+		"  done",
+		"fi",
+	)
+	return strings.Join(steps, "\n")
+}
+
 // SetConfig stores the KaasConfig object internally as part of the cluster
 func (c Cluster) SetConfig(config *KaasConfig) Cluster {
 	c.KaasConfig = config
 	return c
 }
 
+// DefaultImage returns the node image used for the Cluster's type when spec.image is empty
+func (c Cluster) DefaultImage() string {
+	if c.Status.Defaults != nil && c.Status.Defaults.Image != "" {
+		return c.Status.Defaults.Image
+	}
+	if c.KaasConfig != nil && c.KaasConfig.DefaultImages[c.Spec.ClusterType] != "" {
+		return c.KaasConfig.DefaultImages[c.Spec.ClusterType]
+	}
+
+	if provisioner, ok := c.Provisioner(); ok {
+		return provisioner.DefaultImage()
+	}
+	return ""
+}
+
+// Image returns the image the Cluster runs, falling back to the default for its type
+func (c Cluster) Image() string {
+	if c.Spec.Image != "" {
+		return c.Spec.Image
+	}
+	return c.DefaultImage()
+}
+
+// PrimaryContainer is the name of the Cluster pod's container that bootstraps the
+// nested cluster, which is the one exec'd into and whose readiness counts
+func (c Cluster) PrimaryContainer() string {
+	return c.Pod(c.Namespace).Spec.Containers[0].Name
+}
+
+// Provisioner returns the Provisioner registered for the Cluster's type
+func (c Cluster) Provisioner() (Provisioner, bool) {
+	return ProvisionerFor(c.Spec.ClusterType)
+}
+
+// ServiceType returns how the nested API server should be exposed
+func (c Cluster) ServiceType() v1.ServiceType {
+	if c.Spec.ServiceType != "" {
+		return c.Spec.ServiceType
+	}
+	if c.Status.Defaults != nil && c.Status.Defaults.ServiceType != "" {
+		return c.Status.Defaults.ServiceType
+	}
+	if c.KaasConfig != nil && c.KaasConfig.DefaultServiceType != "" {
+		return c.KaasConfig.DefaultServiceType
+	}
+	return v1.ServiceTypeNodePort
+}
+
+// SetDefaults writes the effective image, resources, service type and tool pins into the spec
+// so the stored Cluster shows exactly what will run
+func (c *Cluster) SetDefaults() {
+	if c.Spec.Image == "" {
+		c.Spec.Image = c.DefaultImage()
+	}
+
+	if c.KaasConfig != nil {
+		if c.Spec.CPU == nil && c.KaasConfig.DefaultCPU != nil {
+			cpu := c.KaasConfig.DefaultCPU.DeepCopy()
+			c.Spec.CPU = &cpu
+		}
+		if c.Spec.Memory == nil && c.KaasConfig.DefaultMemory != nil {
+			memory := c.KaasConfig.DefaultMemory.DeepCopy()
+			c.Spec.Memory = &memory
+		}
+	}
+
+	c.setToolDefaults()
+
+	c.Spec.ServiceType = c.ServiceType()
+
+	if c.Spec.RestartPolicy == "" {
+		c.Spec.RestartPolicy = RestartPolicyNever
+	}
+	if c.Spec.RestartPolicy == RestartPolicyRecreate && c.Spec.MaxRetries == nil {
+		maxRetries := int32(DefaultMaxRetries)
+		c.Spec.MaxRetries = &maxRetries
+	}
+}
+
+// SnapshotDefaults records the KaasConfig defaults the Cluster currently relies on, the first
+// time it's called. From then on the Cluster keeps rendering the same pod and Service whatever
+// happens to the KaasConfig, since a changed default would otherwise recreate the pod.
+func (c *Cluster) SnapshotDefaults() {
+	if c.Status.Defaults != nil {
+		return
+	}
+	port := c.servicePort()
+	defaults := &ClusterDefaults{
+		Image:       c.DefaultImage(),
+		ServiceType: c.ServiceType(),
+		Port:        &port,
+	}
+	if c.KaasConfig != nil {
+		defaults.Tools = c.KaasConfig.Tools.DeepCopy()
+	}
+	c.Status.Defaults = defaults
+}
+
+// Expiry returns when the Cluster should be deleted, or nil if it never expires.
+// The earliest of spec.expiresAt and creation + spec.ttl (or the KaasConfig default TTL) wins,
+// and the KaasConfig max TTL caps whatever the spec asks for.
+func (c Cluster) Expiry() *metav1.Time {
+	var expiry *time.Time
+	earliest := func(t time.Time) {
+		if expiry == nil || t.Before(*expiry) {
+			expiry = &t
+		}
+	}
+
+	ttl := c.Spec.TTL
+	if ttl == nil && c.Spec.ExpiresAt == nil && c.KaasConfig != nil {
+		ttl = c.KaasConfig.DefaultTTL
+	}
+	if ttl != nil {
+		earliest(c.CreationTimestamp.Add(ttl.Duration))
+	}
+	if c.Spec.ExpiresAt != nil {
+		earliest(c.Spec.ExpiresAt.Time)
+	}
+	if c.KaasConfig != nil && c.KaasConfig.MaxTTL != nil {
+		earliest(c.CreationTimestamp.Add(c.KaasConfig.MaxTTL.Duration))
+	}
+
+	if expiry == nil {
+		return nil
+	}
+	expiresAt := metav1.NewTime(*expiry)
+	return &expiresAt
+}
+
 // PodSpecEquals accepts a pod and returns a bool whether the
 // podSpec is the same as a generated podSpec.
+func (c Cluster) PodSpecEquals(foundPod *corev1.Pod) bool {
+	return len(c.PodSpecDiff(foundPod)) == 0
+}
+
+// PodSpecDiff returns the parts of the given pod that differ from a generated podSpec.
 // Since we have no way to DeepEquals podSpecs, we have to
 // handle this ourselves. God dammit.
-func (c Cluster) PodSpecEquals(foundPod *corev1.Pod) bool {
-	pod := c.Pod(foundPod.Namespace)
+func (c Cluster) PodSpecDiff(foundPod *corev1.Pod) []string {
+	return podSpecDiff(c.Pod(foundPod.Namespace), foundPod)
+}
 
-	// Check that commands haven't changed (due to clusterYAML changing)
-	if !reflect.DeepEqual(pod.Spec.Containers[0].Command, foundPod.Spec.Containers[0].Command) {
-		log.Print("Container commands not equal")
-		log.Printf("%v", pod.Spec.Containers[0].Command)
-		log.Printf("%v", foundPod.Spec.Containers[0].Command)
-		return false
+// podSpecDiff returns the parts of foundPod that differ from the generated pod
+func podSpecDiff(pod, foundPod *corev1.Pod) []string {
+	var diff []string
+
+	if len(pod.Spec.Containers) != len(foundPod.Spec.Containers) {
+		log.Printf("Container count not equal: %d != %d", len(pod.Spec.Containers), len(foundPod.Spec.Containers))
+		return append(diff, "containers")
 	}
 
-	// Check that the CPU and Memory haven't changed
-	if !pod.Spec.Containers[0].Resources.Limits.Cpu().Equal(*foundPod.Spec.Containers[0].Resources.Limits.Cpu()) {
-		log.Printf("CPU not equal: `%v` != `%v`", pod.Spec.Containers[0].Resources.Limits.Cpu(), foundPod.Spec.Containers[0].Resources.Limits.Cpu())
-		return false
+	changed := make(map[string]bool)
+	for i, container := range pod.Spec.Containers {
+		found := foundPod.Spec.Containers[i]
+
+		// Check that commands haven't changed (due to image or clusterYAML changing)
+		if !reflect.DeepEqual(container.Command, found.Command) || !reflect.DeepEqual(container.Args, found.Args) {
+			log.Printf("Container %s commands not equal", container.Name)
+			log.Printf("%v", container.Command)
+			log.Printf("%v", found.Command)
+			changed["command"] = true
+		}
+		if container.Image != found.Image {
+			log.Printf("Container %s image not equal: `%s` != `%s`", container.Name, container.Image, found.Image)
+			changed["image"] = true
+		}
+
+		// Check that the CPU and Memory haven't changed
+		if !container.Resources.Limits.Cpu().Equal(*found.Resources.Limits.Cpu()) {
+			log.Printf("CPU not equal: `%v` != `%v`", container.Resources.Limits.Cpu(), found.Resources.Limits.Cpu())
+			changed["cpu"] = true
+		}
+		if !container.Resources.Limits.Memory().Equal(*found.Resources.Limits.Memory()) {
+			log.Printf("Memory not equal: `%v` != `%v`", container.Resources.Limits.Memory(), found.Resources.Limits.Memory())
+			changed["memory"] = true
+		}
 	}
-	if !pod.Spec.Containers[0].Resources.Limits.Memory().Equal(*foundPod.Spec.Containers[0].Resources.Limits.Memory()) {
-		log.Printf("Memory not equal: `%v` != `%v`", pod.Spec.Containers[0].Resources.Limits.Memory(), foundPod.Spec.Containers[0].Resources.Limits.Memory())
-		return false
+
+	for _, field := range []string{"command", "image", "cpu", "memory"} {
+		if changed[field] {
+			diff = append(diff, field)
+		}
+	}
+	return diff
+}
+
+// RecreateChanges lists why moving from the old Cluster to this one would make
+// the controller replace the Cluster pod, destroying the nested cluster
+func (c Cluster) RecreateChanges(old Cluster) []string {
+	changes := c.PodSpecDiff(old.Pod(old.Namespace))
+
+	if !reflect.DeepEqual(c.ConfigMap(c.Namespace).Data, old.ConfigMap(old.Namespace).Data) {
+		changes = append(changes, "config files")
 	}
 
-	return true
+	return changes
 }
 
 // ConfigMap generates a ConfigMap based on the Cluster's Spec
@@ -69,13 +294,15 @@ func (c Cluster) ConfigMap(namespace string) *corev1.ConfigMap {
 
 	cm.Data = make(map[string]string)
 
-	if c.Spec.ClusterType == KindCluster {
-		kindConfig, err := c.KindConfig()
+	if provisioner, ok := c.Provisioner(); ok {
+		files, err := provisioner.ConfigFiles(c)
 		if err != nil {
-			log.Printf("Error getting KindConfig: %s", err.Error())
+			log.Printf("Error getting %s config files: %s", c.Spec.ClusterType, err.Error())
 		}
 
-		cm.Data["kind-config.yaml"] = kindConfig
+		for name, data := range files {
+			cm.Data[name] = data
+		}
 	}
 
 	for key, data := range c.Spec.ClusterYAML {
@@ -85,168 +312,227 @@ func (c Cluster) ConfigMap(namespace string) *corev1.ConfigMap {
 	return cm
 }
 
-// KindConfig generates a valid KindConfig, makes updates, then returns a string
-func (c Cluster) KindConfig() (string, error) {
-	kindConfig := &v1alpha4.Cluster{}
+// Pod generates a Pod based on the Cluster Spec
+func (c Cluster) Pod(namespace string) *corev1.Pod {
+	labels := c.GetLabels()
+	if len(labels) == 0 {
+		labels = make(map[string]string)
+	}
+	labels["cluster"] = c.Name
+
+	provisioner, _ := c.Provisioner()
+	var spec v1.PodSpec
+	if builder, ok := provisioner.(PodBuilder); ok {
+		spec = builder.PodSpec(c)
+	} else {
+		spec = c.dindPodSpec(provisioner)
+	}
 
-	err := yaml.Unmarshal([]byte(c.Spec.ClusterSpec), kindConfig)
-	if err != nil {
-		return "", fmt.Errorf("error unmarshalling kindconfig: %s", err.Error())
+	return &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.Name,
+			Namespace: namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&c, SchemeBuilder.GroupVersion.WithKind("Cluster")),
+			},
+		},
+		Spec: spec,
 	}
+}
 
-	kindConfig.Networking.APIServerPort = 6443
-	kindConfig.Networking.APIServerAddress = "0.0.0.0"
+// resources are the limits and requests of the container running the nested cluster
+func (c Cluster) resources() v1.ResourceRequirements {
+	resourceList := v1.ResourceList{}
+	if c.Spec.CPU != nil {
+		resourceList[v1.ResourceCPU] = *c.Spec.CPU
+	}
+	if c.Spec.Memory != nil {
+		resourceList[v1.ResourceMemory] = *c.Spec.Memory
+	}
+	return v1.ResourceRequirements{
+		Limits:   resourceList,
+		Requests: resourceList,
+	}
+}
 
-	data, err := yaml.Marshal(kindConfig)
-	if err != nil {
-		return "", fmt.Errorf("error marshalling kindconfig: %s", err.Error())
+// AgentSelector selects the Cluster's agent pods. They deliberately don't carry the
+// "cluster" label, so the Service only ever points at the Cluster pod.
+func (c Cluster) AgentSelector() map[string]string {
+	return map[string]string{
+		"cluster-agent": c.Name,
 	}
+}
 
-	return string(data), nil
+// AgentPods generates spec.workers agent pods for Provisioners that implement AgentBuilder
+func (c Cluster) AgentPods(namespace string) []*corev1.Pod {
+	provisioner, _ := c.Provisioner()
+	builder, ok := provisioner.(AgentBuilder)
+	if !ok || c.Spec.Workers == nil {
+		return nil
+	}
+
+	var pods []*corev1.Pod
+	for i := int32(0); i < *c.Spec.Workers; i++ {
+		labels := make(map[string]string)
+		for k, v := range c.GetLabels() {
+			labels[k] = v
+		}
+		delete(labels, "cluster")
+		for k, v := range c.AgentSelector() {
+			labels[k] = v
+		}
+
+		pods = append(pods, &v1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-agent-%d", c.Name, i),
+				Namespace: namespace,
+				Labels:    labels,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(&c, SchemeBuilder.GroupVersion.WithKind("Cluster")),
+				},
+			},
+			Spec: builder.AgentPodSpec(c),
+		})
+	}
+	return pods
 }
 
-// Pod generates a Pod based on the Cluster Spec
-func (c Cluster) Pod(namespace string) *corev1.Pod {
-	command := "sleep 5 && curl -sSLo /root/add_sa.sh https://gist.githubusercontent.com/jeefy/81fb5bc9b95898c1492d796a8a27ab10/raw/374f0cf09a6a6eceb5ae982bbd5df39dab7804e5/kubernetes_add_service_account_kubeconfig.sh && chmod +x /root/add_sa.sh && apt update && apt install -y jq && mkdir -p /root/.kube/ && "
+// AgentPodSpecDiff returns the parts of the given agent pod that differ from a generated one
+func (c Cluster) AgentPodSpecDiff(foundPod *corev1.Pod) []string {
+	for _, pod := range c.AgentPods(foundPod.Namespace) {
+		if pod.Name == foundPod.Name {
+			return podSpecDiff(pod, foundPod)
+		}
+	}
+	return []string{"workers"}
+}
+
+// dindPodSpec runs the provisioner's bootstrap command in a privileged Docker-in-Docker
+// container, then adds a service account kubeconfig and applies the clusterYAML manifests
+func (c Cluster) dindPodSpec(provisioner Provisioner) v1.PodSpec {
+	command := "mkdir -p " + progressDir + " && "
+	if c.Spec.WaitForRollout {
+		command += fmt.Sprintf("touch %s/%s && ", progressDir, waitForRolloutMarker)
+	}
+	command += "sleep 5 && curl -sSLo /root/add_sa.sh https://gist.githubusercontent.com/jeefy/81fb5bc9b95898c1492d796a8a27ab10/raw/374f0cf09a6a6eceb5ae982bbd5df39dab7804e5/kubernetes_add_service_account_kubeconfig.sh && chmod +x /root/add_sa.sh && apt update && apt install -y jq && mkdir -p /root/.kube/ && "
 	defaultMode := int32(0777)
 	falseValue := false
-	resourceList := v1.ResourceList{}
-	resourceList[v1.ResourceCPU] = *c.Spec.CPU
-	resourceList[v1.ResourceMemory] = *c.Spec.Memory
-	image := c.Spec.Image
-	switch c.Spec.ClusterType {
-	case KindCluster:
-		if c.Spec.Image == "" {
-			image = "kindest/node:v1.18.0"
-		}
-		command += fmt.Sprintf("curl -sSLo \"${PATH%%%%:*}/kind\" https://storage.googleapis.com/bentheelder-kind-ci-builds/latest/kind-linux-amd64 && chmod +x \"${PATH%%%%:*}/kind\" && kind create cluster --image %s --config=/honk/kind-config.yaml && ", image)
-	case K3sCluster:
-		if c.Spec.Image == "" {
-			image = "rancher/k3s:v1.18.2-rc1-k3s1"
-		}
-		command += fmt.Sprintf("curl -s https://raw.githubusercontent.com/rancher/k3d/master/install.sh | bash && k3d create --image %s --api-port 6443 && sleep 30 && cp -ruf $(k3d get-kubeconfig) /root/.kube/config && ", image)
+	readinessCheck := DefaultReadinessCheck
+	if install := c.InstallTool(ToolKubectl); install != "" {
+		command += install + " && "
+	}
+	if provisioner != nil {
+		command += provisioner.BootstrapCommand(c) + " && "
+		readinessCheck = provisioner.ReadinessCheck(c)
 	}
 
 	command += "sleep 5 && /root/add_sa.sh kind-user default && sleep 5 && "
+	command += fmt.Sprintf("touch %s/%s && ", progressDir, bootstrappedMarker)
 
 	for key := range c.Spec.ClusterYAML {
 		command += fmt.Sprintf("kubectl apply -f /honk/%d.yaml && sleep 5 && ", key)
 	}
 	//  && kubectl apply -f /honk/01.yaml && sleep 5 && kubectl apply -f /honk/02.yaml && sleep 5 && kubectl apply -f /honk/03.yaml"
-	command += " kubectl create ns honk && sleep infinity"
+	command += fmt.Sprintf(" kubectl create ns honk && touch %s/%s && sleep infinity", progressDir, manifestsAppliedMarker)
 
 	trueValue := true
 	securityContext := v1.SecurityContext{
 		Privileged: &trueValue,
 	}
 
-	labels := c.GetLabels()
-	if len(labels) == 0 {
-		labels = make(map[string]string)
-	}
-	labels["cluster"] = c.Name
-
-	return &v1.Pod{
-		TypeMeta: metav1.TypeMeta{
-			Kind: "pod",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      c.Name,
-			Namespace: namespace,
-			Labels:    labels,
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(&c, SchemeBuilder.GroupVersion.WithKind("Cluster")),
-			},
-		},
-		Spec: v1.PodSpec{
-			//			RuntimeClassName: &runtimeClass,
-			AutomountServiceAccountToken: &falseValue,
-			EnableServiceLinks:           &falseValue,
-			Containers: []v1.Container{
-				{
-					Name:            "kind",
-					Image:           "gcr.io/k8s-testimages/krte@sha256:6cae666d578e2ad87f25934efa7b0a907827cf2cd515067c49e6144954b9cb70",
-					SecurityContext: &securityContext,
-					Command: []string{
-						"wrapper.sh",
-						"bash",
-						"-c",
-						command,
-					},
-					ReadinessProbe: &v1.Probe{
-						InitialDelaySeconds: 120,
-						TimeoutSeconds:      5,
-						Handler: v1.Handler{
-							Exec: &v1.ExecAction{
-								Command: []string{
-									"kubectl",
-									"get",
-									"ns",
-									"honk",
-								},
+	return v1.PodSpec{
+		//			RuntimeClassName: &runtimeClass,
+		AutomountServiceAccountToken: &falseValue,
+		EnableServiceLinks:           &falseValue,
+		// A failed bootstrap should fail the pod rather than crash-loop, the controller decides whether to retry
+		RestartPolicy: v1.RestartPolicyNever,
+		Containers: []v1.Container{
+			{
+				Name:            "kind",
+				Image:           "gcr.io/k8s-testimages/krte@sha256:6cae666d578e2ad87f25934efa7b0a907827cf2cd515067c49e6144954b9cb70",
+				SecurityContext: &securityContext,
+				Command: []string{
+					"wrapper.sh",
+					"bash",
+					"-c",
+					command,
+				},
+				ReadinessProbe: &v1.Probe{
+					InitialDelaySeconds: 120,
+					TimeoutSeconds:      30,
+					Handler: v1.Handler{
+						Exec: &v1.ExecAction{
+							Command: []string{
+								"bash",
+								"-c",
+								readinessCheck,
 							},
 						},
 					},
-					Env: []v1.EnvVar{
-						{Name: "DOCKER_IN_DOCKER_ENABLED", Value: "true"},
+				},
+				Env: []v1.EnvVar{
+					{Name: "DOCKER_IN_DOCKER_ENABLED", Value: "true"},
+				},
+				VolumeMounts: []v1.VolumeMount{
+					{
+						Name:      "docker-root",
+						MountPath: "/var/lib/docker",
 					},
-					VolumeMounts: []v1.VolumeMount{
-						{
-							Name:      "docker-root",
-							MountPath: "/var/lib/docker",
-						},
-						{
-							Name:      "modules",
-							MountPath: "/lib/modules",
-							ReadOnly:  true,
-						},
-						{
-							Name:      "cgroup",
-							MountPath: "/sys/fs/cgroup",
-						},
-						{
-							Name:      "honk",
-							MountPath: "/honk",
-						},
+					{
+						Name:      "modules",
+						MountPath: "/lib/modules",
+						ReadOnly:  true,
+					},
+					{
+						Name:      "cgroup",
+						MountPath: "/sys/fs/cgroup",
 					},
-					Resources: v1.ResourceRequirements{
-						Limits:   resourceList,
-						Requests: resourceList,
+					{
+						Name:      "honk",
+						MountPath: "/honk",
 					},
 				},
+				Resources: c.resources(),
 			},
-			Volumes: []v1.Volume{
-				{
-					Name: "docker-root",
-					VolumeSource: v1.VolumeSource{
-						EmptyDir: &v1.EmptyDirVolumeSource{},
-					},
+		},
+		Volumes: []v1.Volume{
+			{
+				Name: "docker-root",
+				VolumeSource: v1.VolumeSource{
+					EmptyDir: &v1.EmptyDirVolumeSource{},
 				},
-				{
-					Name: "modules",
-					VolumeSource: v1.VolumeSource{
-						HostPath: &v1.HostPathVolumeSource{
-							Path: "/lib/modules",
-						},
+			},
+			{
+				Name: "modules",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: "/lib/modules",
 					},
 				},
-				{
-					Name: "cgroup",
-					VolumeSource: v1.VolumeSource{
-						HostPath: &v1.HostPathVolumeSource{
-							Path: "/sys/fs/cgroup",
-						},
+			},
+			{
+				Name: "cgroup",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: "/sys/fs/cgroup",
 					},
 				},
-				{
-					Name: "honk",
-					VolumeSource: v1.VolumeSource{
-						ConfigMap: &v1.ConfigMapVolumeSource{
-							LocalObjectReference: v1.LocalObjectReference{
-								Name: c.Name,
-							},
-							DefaultMode: &defaultMode,
+			},
+			{
+				Name: "honk",
+				VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: c.Name,
 						},
+						DefaultMode: &defaultMode,
 					},
 				},
 			},
@@ -260,23 +546,9 @@ func (c Cluster) Service() (*v1.Service, error) {
 	selector["cluster"] = c.Name
 
 	// Set up the defaults
-	loadBalancerType := v1.ServiceTypeNodePort
-	log.Printf("Default LB Type: %s", c.KaasConfig.DefaultServiceType)
-	if c.KaasConfig.DefaultServiceType != "" {
-		loadBalancerType = c.KaasConfig.DefaultServiceType
-	}
+	loadBalancerType := c.ServiceType()
+	log.Printf("LB Type: %s", loadBalancerType)
 
-	servicePort := v1.ServicePort{
-		Name: "kube-apiserver",
-		Port: 6443,
-		TargetPort: intstr.IntOrString{
-			IntVal: 6443,
-		},
-	}
-	log.Printf("Default service port: %v", c.KaasConfig.DefaultPort)
-	if c.KaasConfig.DefaultPort.Port != 0 {
-		servicePort = c.KaasConfig.DefaultPort
-	}
 	return &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      c.Name,
@@ -287,7 +559,7 @@ func (c Cluster) Service() (*v1.Service, error) {
 		},
 		Spec: v1.ServiceSpec{
 			Ports: []v1.ServicePort{
-				servicePort,
+				c.servicePort(),
 			},
 			Selector: selector,
 			Type:     loadBalancerType,
@@ -295,6 +567,23 @@ func (c Cluster) Service() (*v1.Service, error) {
 	}, nil
 }
 
+// servicePort is the port the Cluster's Service exposes the nested API server on
+func (c Cluster) servicePort() v1.ServicePort {
+	servicePort := v1.ServicePort{
+		Name: "kube-apiserver",
+		Port: 6443,
+		TargetPort: intstr.IntOrString{
+			IntVal: 6443,
+		},
+	}
+	if c.Status.Defaults != nil && c.Status.Defaults.Port != nil {
+		servicePort = *c.Status.Defaults.Port
+	} else if c.KaasConfig != nil && c.KaasConfig.DefaultPort.Port != 0 {
+		servicePort = c.KaasConfig.DefaultPort
+	}
+	return servicePort
+}
+
 // Secret stores a Secret owned by the Cluster
 func (c Cluster) Secret(name string, data map[string]string) (*v1.Secret, error) {
 	selector := make(map[string]string)
@@ -312,36 +601,59 @@ func (c Cluster) Secret(name string, data map[string]string) (*v1.Secret, error)
 	}, nil
 }
 
-// Kubeconfig gets the cluster kubeconfigs
+// BootstrapSecret stores the credentials a SecretBuilder generated for the Cluster
+func (c Cluster) BootstrapSecret(data map[string][]byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.BootstrapSecretName(),
+			Namespace: c.Namespace,
+			Labels: map[string]string{
+				"cluster": c.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&c, SchemeBuilder.GroupVersion.WithKind("Cluster")),
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// BootstrapSecretName is the name of the Secret holding the Cluster's generated credentials
+func (c Cluster) BootstrapSecretName() string {
+	return fmt.Sprintf("%s-bootstrap", c.Name)
+}
+
+// APIEndpoint works out the host:port the nested API server is reachable on from outside the pod,
+// or an empty string if it's only reachable from inside it.
 // It makes several assumptions depending on the ServiceType
 // If those assumptions are incorrect.... WELP. It'll just die.
-func (c Cluster) Kubeconfig(config *rest.Config, svc *v1.Service, configs map[string]string) (kubeconfigs map[string]string, err error) {
-	kubeconfigs = make(map[string]string)
-	var port int32
-	ip := "0.0.0.0"
+func (c Cluster) APIEndpoint(config *rest.Config, svc *v1.Service) (string, error) {
 	rand.Seed(112358)
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		log.Printf("Unable to create clientset: %s", err.Error())
-		return kubeconfigs, err
-	}
-
 	// Load Balancers are SO EASY
 	if svc.Spec.Type == v1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) > 0 {
 		log.Printf("Swapping out IP for loadBalancer IP: %s", svc.Status.LoadBalancer.Ingress[0].IP)
-		ip = svc.Status.LoadBalancer.Ingress[0].IP
-		port = svc.Spec.Ports[0].Port
+		return fmt.Sprintf("%s:%d", svc.Status.LoadBalancer.Ingress[0].IP, svc.Spec.Ports[0].Port), nil
 	}
 
 	// Otherwise easiest is a NodePort
 	if svc.Spec.Type == v1.ServiceTypeNodePort {
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			log.Printf("Unable to create clientset: %s", err.Error())
+			return "", err
+		}
+
 		// Nodes can have multiple IPs, let's handle that
 		internalAddress := ""
 		externalAddress := ""
 		nodes, err := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
 		if err != nil {
-			return nil, err
+			return "", err
+		}
+		if len(nodes.Items) == 0 {
+			return "", fmt.Errorf("no nodes to reach the NodePort Service %s through", svc.Name)
 		}
 
 		// Let's select a random node since it'll all go to the same place anyway
@@ -356,15 +668,40 @@ func (c Cluster) Kubeconfig(config *rest.Config, svc *v1.Service, configs map[st
 			}
 		}
 		// Set the default to the internal address first
-		ip = internalAddress
+		ip := internalAddress
 
 		// If a node has an external address, that takes precedence
 		if externalAddress != "" {
 			ip = externalAddress
 		}
-		port = svc.Spec.Ports[0].NodePort
+		port := svc.Spec.Ports[0].NodePort
 		log.Printf("Swapping out IP/Port for NodePort IP/Port: %s:%d", ip, port)
+		return fmt.Sprintf("%s:%d", ip, port), nil
+	}
+
+	return "", nil
+}
+
+// OverriddenFields lists the parts of the Cluster's config its Provisioner replaces
+func (c Cluster) OverriddenFields() []string {
+	provisioner, _ := c.Provisioner()
+	if overrider, ok := provisioner.(Overrider); ok {
+		return overrider.OverriddenFields(c)
 	}
+	return nil
+}
+
+// KubeconfigPaths returns where each kubeconfig stored in the kubeconfig Secret lives inside the Cluster pod
+func (c Cluster) KubeconfigPaths() map[string]string {
+	if provisioner, ok := c.Provisioner(); ok {
+		return provisioner.KubeconfigPaths(c)
+	}
+	return defaultKubeconfigPaths()
+}
+
+// Kubeconfig gets the cluster kubeconfigs, pointing them at the given API endpoint
+func (c Cluster) Kubeconfig(config *rest.Config, endpoint string, configs map[string]string) (kubeconfigs map[string]string, err error) {
+	kubeconfigs = make(map[string]string)
 
 	for k, v := range configs {
 		log.Printf("Catting file %s", v)
@@ -372,16 +709,378 @@ func (c Cluster) Kubeconfig(config *rest.Config, svc *v1.Service, configs map[st
 		if err != nil {
 			return nil, err
 		}
-		if port > 0 {
-			kubeconfigs[k] = strings.Replace(data, "0.0.0.0:6443", fmt.Sprintf("%s:%d", ip, port), -1)
-		} else {
-			kubeconfigs[k] = strings.Replace(data, "0.0.0.0", ip, -1)
+		if endpoint != "" {
+			data = strings.Replace(data, "0.0.0.0:6443", endpoint, -1)
 		}
+		kubeconfigs[k] = data
 	}
 
 	return kubeconfigs, nil
 }
 
+// Progress reports which bootstrap steps have finished inside the Cluster pod
+func (c Cluster) Progress(config *rest.Config) (bootstrapped bool, manifestsApplied bool, err error) {
+	markers, err := c.markers(config)
+	if err != nil {
+		return false, false, err
+	}
+
+	for _, marker := range markers {
+		switch marker {
+		case bootstrappedMarker:
+			bootstrapped = true
+		case manifestsAppliedMarker:
+			manifestsApplied = true
+		}
+	}
+
+	return bootstrapped, manifestsApplied, nil
+}
+
+// ManagedNodes returns how many nodes the controller keeps in the nested cluster, and
+// whether it manages them at all
+func (c Cluster) ManagedNodes() (int32, bool) {
+	provisioner, _ := c.Provisioner()
+	manager, ok := provisioner.(NodeManager)
+	if !ok {
+		return 0, false
+	}
+	return manager.Workers(c), true
+}
+
+// SyncNodes creates and deletes nodes in the nested cluster from inside the Cluster pod until
+// there are as many as ManagedNodes says
+func (c Cluster) SyncNodes(config *rest.Config) error {
+	provisioner, _ := c.Provisioner()
+	manager, ok := provisioner.(NodeManager)
+	if !ok {
+		return nil
+	}
+
+	_, err := c.execCommand(config, []string{"sh", "-c", manager.NodesCommand(c)})
+	return err
+}
+
+// nestedStatusCommand fails unless the nested API server passes /readyz, then prints its
+// version, its nodes and the kube-system pods as a stream of JSON documents
+const nestedStatusCommand = "kubectl --request-timeout=10s get --raw /readyz >/dev/null" +
+	" && kubectl --request-timeout=10s get --raw /version" +
+	" && kubectl --request-timeout=10s get nodes -o json" +
+	" && kubectl --request-timeout=10s -n kube-system get pods -o json"
+
+// NestedStatus asks the nested API server for its version, nodes and kube-system pods. It runs
+// kubectl inside the Cluster pod, the same as the readiness probe, so it works whatever the
+// Service type and without the Service's address being in the serving certificate.
+func (c Cluster) NestedStatus(config *rest.Config) (string, *v1.NodeList, *v1.PodList, error) {
+	data, err := c.execCommand(config, []string{"sh", "-c", nestedStatusCommand})
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("/readyz failed: %s", err.Error())
+	}
+	return parseNestedStatus(data)
+}
+
+// parseNestedStatus reads what nestedStatusCommand printed
+func parseNestedStatus(data string) (string, *v1.NodeList, *v1.PodList, error) {
+	info, nodes, pods := version.Info{}, &v1.NodeList{}, &v1.PodList{}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	for _, into := range []interface{}{&info, nodes, pods} {
+		if err := decoder.Decode(into); err != nil {
+			return "", nil, nil, fmt.Errorf("can't read the nested cluster's status: %s", err.Error())
+		}
+	}
+	return info.GitVersion, nodes, pods, nil
+}
+
+// markers lists the marker files in the Cluster pod's progress directory
+func (c Cluster) markers(config *rest.Config) ([]string, error) {
+	data, err := c.execCommand(config, []string{"sh", "-c", fmt.Sprintf("ls %s 2>/dev/null || true", progressDir)})
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(data), nil
+}
+
+// ProvisioningTimeout returns how long the Cluster pod may take to become ready
+func (c Cluster) ProvisioningTimeout() time.Duration {
+	if c.KaasConfig != nil && c.KaasConfig.ProvisioningTimeout != nil {
+		return c.KaasConfig.ProvisioningTimeout.Duration
+	}
+	return DefaultProvisioningTimeout
+}
+
+// CanRetry returns whether a failed Cluster pod should be recreated rather than giving up
+func (c Cluster) CanRetry() bool {
+	if c.Spec.RestartPolicy != RestartPolicyRecreate {
+		return false
+	}
+
+	maxRetries := int32(DefaultMaxRetries)
+	if c.Spec.MaxRetries != nil {
+		maxRetries = *c.Spec.MaxRetries
+	}
+	return c.Status.Attempts < maxRetries
+}
+
+// HealthCheckInterval returns how often a ready Cluster's nested cluster is checked on
+func (c Cluster) HealthCheckInterval() time.Duration {
+	if c.KaasConfig != nil && c.KaasConfig.HealthCheckInterval != nil {
+		return c.KaasConfig.HealthCheckInterval.Duration
+	}
+	return DefaultHealthCheckInterval
+}
+
+// RequeueBackoff returns how long to wait before checking on a Cluster that has
+// been provisioning for the given time. The interval doubles as provisioning drags on.
+func (c Cluster) RequeueBackoff(elapsed time.Duration) time.Duration {
+	interval, max := DefaultRequeueInterval, DefaultMaxRequeueInterval
+	if c.KaasConfig != nil && c.KaasConfig.RequeueInterval != nil {
+		interval = c.KaasConfig.RequeueInterval.Duration
+	}
+	if c.KaasConfig != nil && c.KaasConfig.MaxRequeueInterval != nil {
+		max = c.KaasConfig.MaxRequeueInterval.Duration
+	}
+
+	for interval > 0 && interval*2 <= elapsed && interval < max {
+		interval *= 2
+	}
+	if interval > max {
+		interval = max
+	}
+	return interval
+}
+
+// TeardownTimeout returns how long a teardown of the nested cluster may take
+func (c Cluster) TeardownTimeout() time.Duration {
+	if c.KaasConfig != nil && c.KaasConfig.TeardownTimeout != nil {
+		return c.KaasConfig.TeardownTimeout.Duration
+	}
+	return DefaultTeardownTimeout
+}
+
+// TeardownLogsTTL returns how long the logs exported by a teardown are kept
+func (c Cluster) TeardownLogsTTL() time.Duration {
+	if c.KaasConfig != nil && c.KaasConfig.TeardownLogsTTL != nil {
+		return c.KaasConfig.TeardownLogsTTL.Duration
+	}
+	return DefaultTeardownLogsTTL
+}
+
+// HasTeardown returns whether anything needs to run in the Cluster pod before it's deleted
+func (c Cluster) HasTeardown() bool {
+	return c.teardownScript() != ""
+}
+
+// teardownScript exports the nested cluster's logs if the KaasConfig asks for it, then
+// cleanly deletes the nested cluster. It exits with the status of the delete.
+func (c Cluster) teardownScript() string {
+	provisioner, ok := c.Provisioner()
+	if !ok {
+		return ""
+	}
+
+	var steps []string
+	if c.KaasConfig != nil && c.KaasConfig.ExportLogsOnTeardown {
+		if command := provisioner.ExportLogsCommand(c, logsDir); command != "" {
+			steps = append(steps, fmt.Sprintf("{ %s && tar -czf - -C %s logs | base64 -w0 >%s; }", command, progressDir, teardownLogsBundle))
+		}
+	}
+	if command := provisioner.TeardownCommand(c); command != "" {
+		steps = append(steps, command)
+	}
+	return strings.Join(steps, "; ")
+}
+
+// StartTeardown starts the teardown in the background inside the Cluster pod and returns
+// straight away. The teardown is killed after the teardown timeout.
+func (c Cluster) StartTeardown(config *rest.Config) error {
+	timeout := int(c.TeardownTimeout().Seconds())
+	done := fmt.Sprintf("%s/%s", progressDir, tornDownMarker)
+	script := fmt.Sprintf("timeout %d sh -c %s >%s 2>&1; echo $? >%s.tmp && mv %s.tmp %s", timeout, shellQuote(c.teardownScript()), teardownLog, done, done, done)
+	command := fmt.Sprintf("mkdir -p %s && touch %s/%s && nohup sh -c %s >/dev/null 2>&1 &", progressDir, progressDir, teardownStartedMarker, shellQuote(script))
+
+	_, err := c.execCommand(config, []string{"sh", "-c", command})
+	return err
+}
+
+// TeardownProgress reports whether a teardown has been started in the Cluster pod and whether it has finished
+func (c Cluster) TeardownProgress(config *rest.Config) (started bool, finished bool, err error) {
+	markers, err := c.markers(config)
+	if err != nil {
+		return false, false, err
+	}
+
+	for _, marker := range markers {
+		switch marker {
+		case teardownStartedMarker:
+			started = true
+		case tornDownMarker:
+			finished = true
+		}
+	}
+
+	return started, finished, nil
+}
+
+// TeardownResult returns the output of a finished teardown, and an error if it failed
+func (c Cluster) TeardownResult(config *rest.Config) (string, error) {
+	output, err := c.catFile(config, teardownLog)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := c.catFile(config, fmt.Sprintf("%s/%s", progressDir, tornDownMarker))
+	if err != nil {
+		return output, err
+	}
+	switch code = strings.TrimSpace(code); code {
+	case "0":
+		return output, nil
+	case "124":
+		return output, fmt.Errorf("teardown timed out after %s", c.TeardownTimeout())
+	default:
+		return output, fmt.Errorf("teardown exited with code %s", code)
+	}
+}
+
+// TeardownLogs returns the logs a finished teardown exported as a gzipped tarball
+func (c Cluster) TeardownLogs(config *rest.Config) ([]byte, error) {
+	data, err := c.catFile(config, teardownLogsBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err == nil && len(bundle) == 0 {
+		err = fmt.Errorf("no logs were exported")
+	}
+	return bundle, err
+}
+
+// logExportScript exports the nested cluster's logs and leaves them in the exported logs bundle
+func (c Cluster) logExportScript() string {
+	provisioner, ok := c.Provisioner()
+	if !ok {
+		return ""
+	}
+
+	command := provisioner.ExportLogsCommand(c, logsDir)
+	if command == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s && tar -czf - -C %s logs | base64 -w0 >%s", command, progressDir, exportedLogsBundle)
+}
+
+// StartLogExport starts exporting the nested cluster's logs in the background inside the
+// Cluster pod and returns straight away. The export is killed after the teardown timeout.
+func (c Cluster) StartLogExport(config *rest.Config) error {
+	export := c.logExportScript()
+	if export == "" {
+		return fmt.Errorf("can't export logs for cluster type %s", c.Spec.ClusterType)
+	}
+
+	timeout := int(c.TeardownTimeout().Seconds())
+	done := fmt.Sprintf("%s/%s", progressDir, logsExportedMarker)
+	script := fmt.Sprintf("timeout %d sh -c %s >/dev/null 2>&1; echo $? >%s.tmp && mv %s.tmp %s", timeout, shellQuote(export), done, done, done)
+	command := fmt.Sprintf("mkdir -p %s && rm -f %s && nohup sh -c %s >/dev/null 2>&1 &", progressDir, done, shellQuote(script))
+
+	_, err := c.execCommand(config, []string{"sh", "-c", command})
+	return err
+}
+
+// LogExportFinished reports whether a log export started in the Cluster pod has finished
+func (c Cluster) LogExportFinished(config *rest.Config) (bool, error) {
+	markers, err := c.markers(config)
+	if err != nil {
+		return false, err
+	}
+
+	for _, marker := range markers {
+		if marker == logsExportedMarker {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ExportedLogs returns the logs a finished log export collected as a gzipped tarball
+func (c Cluster) ExportedLogs(config *rest.Config) ([]byte, error) {
+	code, err := c.catFile(config, fmt.Sprintf("%s/%s", progressDir, logsExportedMarker))
+	if err != nil {
+		return nil, err
+	}
+	switch code = strings.TrimSpace(code); code {
+	case "0":
+	case "124":
+		return nil, fmt.Errorf("log export timed out after %s", c.TeardownTimeout())
+	default:
+		return nil, fmt.Errorf("log export exited with code %s", code)
+	}
+
+	data, err := c.catFile(config, exportedLogsBundle)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+}
+
+// ContainerLogTail returns the last lines of the Cluster pod's container log, which
+// still works once the container has exited
+func (c Cluster) ContainerLogTail(config *rest.Config, lines int64) (string, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := clientset.CoreV1().Pods(c.Namespace).GetLogs(c.Name, &v1.PodLogOptions{Container: c.PrimaryContainer(), TailLines: &lines}).DoRaw()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LogsConfigMap stores a log bundle for the Cluster in a ConfigMap
+func (c Cluster) LogsConfigMap(name string, bundle []byte) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", c.Name, name),
+			Namespace: c.Namespace,
+			Labels: map[string]string{
+				"cluster": c.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&c, SchemeBuilder.GroupVersion.WithKind("Cluster")),
+			},
+		},
+		BinaryData: map[string][]byte{
+			"logs.tar.gz": bundle,
+		},
+	}
+}
+
+// TeardownLogsConfigMap generates the ConfigMap that keeps a teardown's logs after the Cluster is gone.
+// It isn't owned by the Cluster, otherwise it would be garbage collected along with it, so it's
+// labelled and stamped with when it may be deleted instead.
+func (c Cluster) TeardownLogsConfigMap(bundle []byte, now time.Time) *v1.ConfigMap {
+	cm := c.LogsConfigMap("teardown-logs", bundle)
+	cm.OwnerReferences = nil
+	cm.Labels[TeardownLogsLabel] = "true"
+	cm.Annotations = map[string]string{
+		DeleteAfterAnnotation: now.Add(c.TeardownLogsTTL()).UTC().Format(time.RFC3339),
+	}
+	return cm
+}
+
+// execWithTimeout runs a shell command in the Cluster pod, killing it after the teardown timeout
+func (c Cluster) execWithTimeout(config *rest.Config, command string) (string, error) {
+	timeout := fmt.Sprintf("%d", int(c.TeardownTimeout().Seconds()))
+	return c.execCommand(config, []string{"timeout", timeout, "sh", "-c", command})
+}
+
+// shellQuote quotes s as a single sh word
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
 func (c Cluster) catFile(config *rest.Config, filename string) (data string, err error) {
 	return c.execCommand(config, []string{"cat", filename})
 }
@@ -404,15 +1103,13 @@ func (c Cluster) execCommand(config *rest.Config, command []string) (data string
 
 	req.VersionedParams(&v1.PodExecOptions{
 		Command:   command,
-		Container: "",
+		Container: c.PrimaryContainer(),
 		Stdin:     false,
 		Stdout:    true,
 		Stderr:    true,
 		TTY:       false,
 	}, scheme.ParameterCodec)
 
-	fmt.Println("Request URL:", req.URL().String())
-
 	exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
 	if err != nil {
 		log.Printf("spdy error: %v", err)
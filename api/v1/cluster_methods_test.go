@@ -0,0 +1,696 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func duration(d time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: d}
+}
+
+func TestExpiry(t *testing.T) {
+	created := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(created.Add(d))
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		ttl       *metav1.Duration
+		expiresAt *metav1.Time
+		config    *KaasConfig
+		want      *metav1.Time
+	}{
+		{
+			name: "never expires",
+		},
+		{
+			name:   "never expires with an empty config",
+			config: &KaasConfig{},
+		},
+		{
+			name: "ttl",
+			ttl:  duration(2 * time.Hour),
+			want: at(2 * time.Hour),
+		},
+		{
+			name:      "expiresAt",
+			expiresAt: at(time.Hour),
+			want:      at(time.Hour),
+		},
+		{
+			name:      "expiresAt before ttl",
+			ttl:       duration(2 * time.Hour),
+			expiresAt: at(time.Hour),
+			want:      at(time.Hour),
+		},
+		{
+			name:      "ttl before expiresAt",
+			ttl:       duration(time.Hour),
+			expiresAt: at(2 * time.Hour),
+			want:      at(time.Hour),
+		},
+		{
+			name:   "default ttl",
+			config: &KaasConfig{DefaultTTL: duration(3 * time.Hour)},
+			want:   at(3 * time.Hour),
+		},
+		{
+			name:   "spec ttl replaces the default ttl",
+			ttl:    duration(5 * time.Hour),
+			config: &KaasConfig{DefaultTTL: duration(3 * time.Hour)},
+			want:   at(5 * time.Hour),
+		},
+		{
+			name:      "expiresAt replaces the default ttl",
+			expiresAt: at(5 * time.Hour),
+			config:    &KaasConfig{DefaultTTL: duration(3 * time.Hour)},
+			want:      at(5 * time.Hour),
+		},
+		{
+			name:   "max ttl caps the spec",
+			ttl:    duration(48 * time.Hour),
+			config: &KaasConfig{MaxTTL: duration(24 * time.Hour)},
+			want:   at(24 * time.Hour),
+		},
+		{
+			name:   "max ttl applies without a spec ttl",
+			config: &KaasConfig{MaxTTL: duration(24 * time.Hour)},
+			want:   at(24 * time.Hour),
+		},
+		{
+			name:   "max ttl leaves a shorter ttl alone",
+			ttl:    duration(time.Hour),
+			config: &KaasConfig{MaxTTL: duration(24 * time.Hour)},
+			want:   at(time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.CreationTimestamp = metav1.NewTime(created)
+			c.Spec.TTL = tt.ttl
+			c.Spec.ExpiresAt = tt.expiresAt
+			c.KaasConfig = tt.config
+
+			got := c.Expiry()
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || !got.Equal(tt.want):
+				t.Errorf("Expiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeardownScript(t *testing.T) {
+	tests := []struct {
+		name        string
+		clusterType ClusterType
+		config      *KaasConfig
+		want        string
+	}{
+		{
+			name:        "delete only",
+			clusterType: KindCluster,
+			want:        "kind delete cluster",
+		},
+		{
+			name:        "export logs first",
+			clusterType: KindCluster,
+			config:      &KaasConfig{ExportLogsOnTeardown: true},
+			want:        "{ kind export logs /tmp/kaas/logs >/dev/null && tar -czf - -C /tmp/kaas logs | base64 -w0 >/tmp/kaas/teardown-logs; }; kind delete cluster",
+		},
+		{
+			name:        "nothing to tear down",
+			clusterType: K3sNativeCluster,
+			config:      &KaasConfig{ExportLogsOnTeardown: true},
+		},
+		{
+			name:        "unknown cluster type",
+			clusterType: "kubeadm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = tt.clusterType
+			c.KaasConfig = tt.config
+
+			if got := c.teardownScript(); got != tt.want {
+				t.Errorf("teardownScript() = %q, want %q", got, tt.want)
+			}
+			if got := c.HasTeardown(); got != (tt.want != "") {
+				t.Errorf("HasTeardown() = %v, want %v", got, tt.want != "")
+			}
+		})
+	}
+}
+
+func TestLogExportScript(t *testing.T) {
+	tests := []struct {
+		name        string
+		clusterType ClusterType
+		want        string
+	}{
+		{
+			name:        "kind",
+			clusterType: KindCluster,
+			want:        "kind export logs /tmp/kaas/logs >/dev/null && tar -czf - -C /tmp/kaas logs | base64 -w0 >/tmp/kaas/exported-logs",
+		},
+		{
+			name:        "no log export",
+			clusterType: K3sNativeCluster,
+		},
+		{
+			name:        "unknown cluster type",
+			clusterType: "kubeadm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = tt.clusterType
+
+			if got := c.logExportScript(); got != tt.want {
+				t.Errorf("logExportScript() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeardownLogsConfigMap(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		config *KaasConfig
+		want   string
+	}{
+		{
+			name: "default TTL",
+			want: "2020-05-08T12:00:00Z",
+		},
+		{
+			name:   "KaasConfig TTL",
+			config: &KaasConfig{TeardownLogsTTL: duration(time.Hour)},
+			want:   "2020-05-01T13:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Name = "test"
+			c.Namespace = "default"
+			c.KaasConfig = tt.config
+
+			cm := c.TeardownLogsConfigMap([]byte("logs"), now)
+			if cm.Name != "test-teardown-logs" || len(cm.OwnerReferences) > 0 {
+				t.Errorf("TeardownLogsConfigMap() = %s owned by %v, want test-teardown-logs owned by nothing", cm.Name, cm.OwnerReferences)
+			}
+			if cm.Labels[TeardownLogsLabel] != "true" {
+				t.Errorf("TeardownLogsConfigMap() labels = %v, want %s", cm.Labels, TeardownLogsLabel)
+			}
+			if got := cm.Annotations[DeleteAfterAnnotation]; got != tt.want {
+				t.Errorf("TeardownLogsConfigMap() %s = %q, want %q", DeleteAfterAnnotation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                     "''",
+		"kind delete cluster":  "'kind delete cluster'",
+		"sed -i 's/a/b/' file": `'sed -i '\''s/a/b/'\'' file'`,
+		`echo "$HOME" && exit`: `'echo "$HOME" && exit'`,
+	}
+
+	for s, want := range tests {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestRequeueBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		config  *KaasConfig
+		want    time.Duration
+	}{
+		{
+			name: "just created",
+			want: DefaultRequeueInterval,
+		},
+		{
+			name:    "not yet twice the interval",
+			elapsed: 9 * time.Second,
+			want:    DefaultRequeueInterval,
+		},
+		{
+			name:    "doubles",
+			elapsed: 10 * time.Second,
+			want:    10 * time.Second,
+		},
+		{
+			name:    "keeps doubling",
+			elapsed: 45 * time.Second,
+			want:    40 * time.Second,
+		},
+		{
+			name:    "capped",
+			elapsed: time.Hour,
+			want:    DefaultMaxRequeueInterval,
+		},
+		{
+			name:    "configured interval",
+			elapsed: 3 * time.Second,
+			config:  &KaasConfig{RequeueInterval: duration(time.Second)},
+			want:    2 * time.Second,
+		},
+		{
+			name:    "configured cap",
+			elapsed: time.Hour,
+			config:  &KaasConfig{MaxRequeueInterval: duration(15 * time.Second)},
+			want:    15 * time.Second,
+		},
+		{
+			name:   "interval above the cap",
+			config: &KaasConfig{RequeueInterval: duration(time.Hour)},
+			want:   DefaultMaxRequeueInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.KaasConfig = tt.config
+
+			if got := c.RequeueBackoff(tt.elapsed); got != tt.want {
+				t.Errorf("RequeueBackoff(%s) = %s, want %s", tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServicePort(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *KaasConfig
+		want   int32
+	}{
+		{
+			name: "no KaasConfig",
+			want: 6443,
+		},
+		{
+			name:   "no default port",
+			config: &KaasConfig{},
+			want:   6443,
+		},
+		{
+			name:   "default port",
+			config: &KaasConfig{DefaultPort: corev1.ServicePort{Name: "kube-apiserver", Port: 443}},
+			want:   443,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.KaasConfig = tt.config
+
+			if got := c.servicePort().Port; got != tt.want {
+				t.Errorf("servicePort().Port = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotDefaults(t *testing.T) {
+	original := &KaasConfig{
+		DefaultImages:      map[ClusterType]string{KindCluster: "kindest/node:v1.17.0"},
+		DefaultServiceType: corev1.ServiceTypeLoadBalancer,
+		DefaultPort:        corev1.ServicePort{Name: "kube-apiserver", Port: 443},
+	}
+	changed := &KaasConfig{
+		DefaultImages:      map[ClusterType]string{KindCluster: "kindest/node:v1.18.0"},
+		DefaultServiceType: corev1.ServiceTypeNodePort,
+	}
+
+	tests := []struct {
+		name            string
+		image           string
+		wantImage       string
+		wantServiceType corev1.ServiceType
+		wantPort        int32
+	}{
+		{
+			name:            "defaults come from the snapshot",
+			wantImage:       "kindest/node:v1.17.0",
+			wantServiceType: corev1.ServiceTypeLoadBalancer,
+			wantPort:        443,
+		},
+		{
+			name:            "the spec still wins",
+			image:           "kindest/node:v1.16.0",
+			wantImage:       "kindest/node:v1.16.0",
+			wantServiceType: corev1.ServiceTypeLoadBalancer,
+			wantPort:        443,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Name = "test"
+			c.Namespace = "default"
+			c.Spec.ClusterType = KindCluster
+			c.Spec.Image = tt.image
+			c.KaasConfig = original
+			c.SnapshotDefaults()
+			pod := c.Pod(c.Namespace)
+
+			c.KaasConfig = changed
+			c.SnapshotDefaults()
+
+			if got := c.Image(); got != tt.wantImage {
+				t.Errorf("Image() = %q, want %q", got, tt.wantImage)
+			}
+			if got := c.ServiceType(); got != tt.wantServiceType {
+				t.Errorf("ServiceType() = %q, want %q", got, tt.wantServiceType)
+			}
+			if got := c.servicePort().Port; got != tt.wantPort {
+				t.Errorf("servicePort().Port = %d, want %d", got, tt.wantPort)
+			}
+			if diff := c.PodSpecDiff(pod); len(diff) > 0 {
+				t.Errorf("PodSpecDiff() = %v after the KaasConfig defaults changed, want none", diff)
+			}
+		})
+	}
+}
+
+func TestAPIEndpoint(t *testing.T) {
+	node := func(addresses ...corev1.NodeAddress) corev1.Node {
+		return corev1.Node{Status: corev1.NodeStatus{Addresses: addresses}}
+	}
+	internal := corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}
+	external := corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "203.0.113.1"}
+
+	tests := []struct {
+		name    string
+		svc     corev1.Service
+		nodes   []corev1.Node
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "load balancer",
+			svc: corev1.Service{
+				Spec:   corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 6443}}},
+				Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "198.51.100.1"}}}},
+			},
+			want: "198.51.100.1:6443",
+		},
+		{
+			name:  "node port on an internal address",
+			svc:   corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: []corev1.ServicePort{{NodePort: 30443}}}},
+			nodes: []corev1.Node{node(internal)},
+			want:  "10.0.0.1:30443",
+		},
+		{
+			name:  "node port prefers an external address",
+			svc:   corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: []corev1.ServicePort{{NodePort: 30443}}}},
+			nodes: []corev1.Node{node(internal, external)},
+			want:  "203.0.113.1:30443",
+		},
+		{
+			name:    "node port without nodes",
+			svc:     corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: []corev1.ServicePort{{NodePort: 30443}}}},
+			wantErr: true,
+		},
+		{
+			name: "cluster IP",
+			svc:  corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(corev1.NodeList{Items: tt.nodes})
+			}))
+			defer server.Close()
+
+			got, err := Cluster{}.APIEndpoint(&rest.Config{Host: server.URL}, &tt.svc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("APIEndpoint() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("APIEndpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNestedStatus(t *testing.T) {
+	const (
+		version = `{"major":"1","minor":"17","gitVersion":"v1.17.2"}`
+		nodes   = `{"apiVersion":"v1","kind":"List","items":[{"metadata":{"name":"a"}},{"metadata":{"name":"b"}}]}`
+		pods    = `{"apiVersion":"v1","kind":"List","items":[{"metadata":{"name":"coredns"}}]}`
+	)
+
+	tests := []struct {
+		name        string
+		data        string
+		wantVersion string
+		wantNodes   int
+		wantPods    int
+		wantErr     bool
+	}{
+		{
+			name:        "kubectl output",
+			data:        version + "\n" + nodes + "\n" + pods + "\n",
+			wantVersion: "v1.17.2",
+			wantNodes:   2,
+			wantPods:    1,
+		},
+		{
+			name:        "no nodes",
+			data:        version + `{"apiVersion":"v1","kind":"List","items":[]}` + pods,
+			wantVersion: "v1.17.2",
+			wantPods:    1,
+		},
+		{
+			name:    "truncated",
+			data:    version + "\n" + nodes + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			data:    "ok\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVersion, gotNodes, gotPods, err := parseNestedStatus(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNestedStatus() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if gotVersion != tt.wantVersion || len(gotNodes.Items) != tt.wantNodes || len(gotPods.Items) != tt.wantPods {
+				t.Errorf("parseNestedStatus() = %s, %d nodes, %d pods, want %s, %d nodes, %d pods", gotVersion, len(gotNodes.Items), len(gotPods.Items), tt.wantVersion, tt.wantNodes, tt.wantPods)
+			}
+		})
+	}
+}
+
+func TestContainerLogTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "log",
+			status: http.StatusOK,
+			want:   "Creating cluster \"kind\" ...\nERROR: failed to create cluster\n",
+		},
+		{
+			name:    "pod gone",
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/namespaces/default/pods/test/log" || r.URL.Query().Get("tailLines") != "200" {
+					t.Errorf("requested %s, want the last 200 lines of the test pod's log", r.URL)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.want))
+			}))
+			defer server.Close()
+
+			c := Cluster{}
+			c.Name, c.Namespace = "test", "default"
+			got, err := c.ContainerLogTail(&rest.Config{Host: server.URL}, 200)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ContainerLogTail() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ContainerLogTail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadinessCheck(t *testing.T) {
+	// kubectl fails when its arguments contain $KUBECTL_FAIL and lists $KUBECTL_OBJECTS for get -f
+	const kubectl = `#!/bin/sh
+if [ -n "$KUBECTL_FAIL" ]; then case "$*" in *"$KUBECTL_FAIL"*) exit 1;; esac; fi
+if [ "$1 $2" = "get -f" ] && [ -n "$KUBECTL_OBJECTS" ]; then echo "$KUBECTL_OBJECTS"; fi
+`
+
+	tests := []struct {
+		name     string
+		markers  []string
+		nodeless bool
+		fail     string
+		objects  string
+		wantErr  bool
+	}{
+		{
+			name:    "still bootstrapping",
+			wantErr: true,
+		},
+		{
+			name:    "ready",
+			markers: []string{manifestsAppliedMarker},
+		},
+		{
+			name:    "API server not ready",
+			markers: []string{manifestsAppliedMarker},
+			fail:    "get --raw /readyz",
+			wantErr: true,
+		},
+		{
+			name:    "nodes not ready",
+			markers: []string{manifestsAppliedMarker},
+			fail:    "wait --for=condition=Ready nodes",
+			wantErr: true,
+		},
+		{
+			name:     "cluster without nodes",
+			markers:  []string{manifestsAppliedMarker},
+			nodeless: true,
+			fail:     "wait --for=condition=Ready nodes",
+		},
+		{
+			name:    "rollouts aren't waited for",
+			markers: []string{manifestsAppliedMarker},
+			fail:    "rollout status",
+			objects: "Deployment <none> web",
+		},
+		{
+			name:    "rolled out",
+			markers: []string{manifestsAppliedMarker, waitForRolloutMarker},
+			objects: "Namespace <none> web\nDeployment web api\nDaemonSet <none> agent",
+		},
+		{
+			name:    "rollout in the default namespace pending",
+			markers: []string{manifestsAppliedMarker, waitForRolloutMarker},
+			fail:    "-n default rollout status DaemonSet/agent",
+			objects: "Deployment web api\nDaemonSet <none> agent",
+			wantErr: true,
+		},
+		{
+			name:    "manifest not applied",
+			markers: []string{manifestsAppliedMarker, waitForRolloutMarker},
+			fail:    "get -f",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "readiness")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for _, sub := range []string{"bin", "kaas", "honk"} {
+				if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "bin", "kubectl"), []byte(kubectl), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "honk", "0.yaml"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, marker := range tt.markers {
+				if err := ioutil.WriteFile(filepath.Join(dir, "kaas", marker), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			script := readinessCheck(!tt.nodeless)
+			script = strings.NewReplacer(progressDir, filepath.Join(dir, "kaas"), "/honk/", filepath.Join(dir, "honk")+"/").Replace(script)
+			cmd := exec.Command("bash", "-c", script)
+			cmd.Env = append(os.Environ(),
+				"PATH="+filepath.Join(dir, "bin")+":"+os.Getenv("PATH"),
+				"KUBECTL_FAIL="+tt.fail,
+				"KUBECTL_OBJECTS="+tt.objects,
+			)
+			if out, err := cmd.CombinedOutput(); (err != nil) != tt.wantErr {
+				t.Errorf("readiness check error = %v, want error %v, output:\n%s", err, tt.wantErr, out)
+			}
+		})
+	}
+}
@@ -15,6 +15,8 @@ limitations under the License.
 package v1
 
 import (
+	"time"
+
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,6 +25,13 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+const (
+	// KaasConfigName is the name of the global KaasConfig object
+	KaasConfigName = "config"
+	// KaasConfigNamespace is the namespace the global KaasConfig object lives in
+	KaasConfigNamespace = "kaas-system"
+)
+
 // +kubebuilder:object:root=true
 
 // KaasConfig contains some global config information used by Kaas
@@ -32,6 +41,39 @@ type KaasConfig struct {
 
 	DefaultServiceType v1.ServiceType `json:"defaultServiceType,omitempty"`
 	DefaultPort        v1.ServicePort `json:"defaultPort,omitempty"`
+
+	// DefaultImages maps a ClusterType to the node image used when spec.image is empty
+	DefaultImages map[ClusterType]string `json:"defaultImages,omitempty"`
+	// DefaultCPU is used when a Cluster doesn't set spec.cpu
+	DefaultCPU *resource.Quantity `json:"defaultCPU,omitempty"`
+	// DefaultMemory is used when a Cluster doesn't set spec.memory
+	DefaultMemory *resource.Quantity `json:"defaultMemory,omitempty"`
+
+	// DefaultTTL is applied to Clusters that don't set spec.ttl or spec.expiresAt
+	DefaultTTL *metav1.Duration `json:"defaultTTL,omitempty"`
+	// MaxTTL caps how long any Cluster may live, regardless of its spec
+	MaxTTL *metav1.Duration `json:"maxTTL,omitempty"`
+
+	// TeardownTimeout is how long the controller waits for a nested cluster to be deleted
+	TeardownTimeout *metav1.Duration `json:"teardownTimeout,omitempty"`
+	// ProvisioningTimeout is how long a Cluster pod may take to become ready before the Cluster is marked Failed
+	ProvisioningTimeout *metav1.Duration `json:"provisioningTimeout,omitempty"`
+	// RequeueInterval is how soon a provisioning Cluster is checked again, doubling the longer it takes
+	RequeueInterval *metav1.Duration `json:"requeueInterval,omitempty"`
+	// MaxRequeueInterval caps the backoff between checks on a provisioning Cluster
+	MaxRequeueInterval *metav1.Duration `json:"maxRequeueInterval,omitempty"`
+	// HealthCheckInterval is how often a ready Cluster's nested API server is checked on
+	HealthCheckInterval *metav1.Duration `json:"healthCheckInterval,omitempty"`
+
+	// ExportLogsOnTeardown saves the nested cluster's logs to a `<name>-teardown-logs` ConfigMap before deleting it
+	ExportLogsOnTeardown bool `json:"exportLogsOnTeardown,omitempty"`
+	// TeardownLogsTTL is how long a `<name>-teardown-logs` ConfigMap is kept before the controller deletes it
+	TeardownLogsTTL *metav1.Duration `json:"teardownLogsTTL,omitempty"`
+
+	// Tools pins the bootstrap tools of Clusters that don't pin their own
+	Tools *BootstrapTools `json:"tools,omitempty"`
+	// RequirePinnedTools refuses Clusters that would download a bootstrap tool without both a version and a SHA-256 checksum
+	RequirePinnedTools bool `json:"requirePinnedTools,omitempty"`
 }
 
 // ClusterType is a list of the types of local clusters we can provision
@@ -42,6 +84,44 @@ const (
 	KindCluster ClusterType = "kind"
 	// K3sCluster is a k3s.io cluster
 	K3sCluster ClusterType = "k3s"
+	// K3sNativeCluster is a k3s.io cluster whose server runs as the Cluster pod and agents as pods of their own
+	K3sNativeCluster ClusterType = "k3s-native"
+	// MinikubeCluster is a minikube cluster using the docker driver
+	MinikubeCluster ClusterType = "minikube"
+	// K0sCluster is a k0sproject.io cluster running a single controller+worker node
+	K0sCluster ClusterType = "k0s"
+	// KwokCluster is a bare control plane with fake nodes simulated by sigs.k8s.io/kwok
+	KwokCluster ClusterType = "kwok"
+	// ControlPlaneCluster is a bare etcd and kube-apiserver, without any nodes
+	ControlPlaneCluster ClusterType = "controlplane"
+)
+
+// ClusterRestartPolicy is what the controller does when a Cluster pod fails
+type ClusterRestartPolicy string
+
+const (
+	// RestartPolicyNever leaves a failed Cluster in the Failed phase
+	RestartPolicyNever ClusterRestartPolicy = "Never"
+	// RestartPolicyRecreate replaces a failed Cluster pod, up to spec.maxRetries times
+	RestartPolicyRecreate ClusterRestartPolicy = "Recreate"
+)
+
+const (
+	// DefaultKindImage is the kind node image used when neither the Cluster nor the KaasConfig set one
+	DefaultKindImage = "kindest/node:v1.18.0"
+	// DefaultK3sImage is the k3s image used when neither the Cluster nor the KaasConfig set one
+	DefaultK3sImage = "rancher/k3s:v1.18.2-rc1-k3s1"
+	// DefaultMinikubeImage is the Kubernetes version minikube runs when neither the Cluster nor the KaasConfig set one
+	DefaultMinikubeImage = "v1.18.0"
+	// DefaultK0sImage is the k0s image used when neither the Cluster nor the KaasConfig set one
+	DefaultK0sImage = "docker.io/k0sproject/k0s:v1.21.2-k0s.1"
+	// DefaultKwokImage is the Kubernetes version kwok clusters run when neither the Cluster nor the KaasConfig set one
+	DefaultKwokImage = "v1.18.0"
+	// DefaultControlPlaneImage is the Kubernetes version controlplane clusters run when neither the Cluster nor the KaasConfig set one
+	DefaultControlPlaneImage = "v1.18.0"
+
+	// DefaultKwokWorkers is how many fake nodes a kwok cluster gets when spec.workers isn't set
+	DefaultKwokWorkers = 1
 )
 
 // ClusterSpec defines the desired state of Cluster
@@ -54,9 +134,349 @@ type ClusterSpec struct {
 
 	Image string `json:"image,omitempty"`
 
-	CPU *resource.Quantity `json:"cpu"`
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+
+	// ServiceType is how the nested API server is exposed
+	ServiceType v1.ServiceType `json:"serviceType,omitempty"`
+
+	// TTL is how long after creation the Cluster is deleted
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// ExpiresAt is an absolute time at which the Cluster is deleted
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// AllowRecreate permits spec changes that make the controller replace the
+	// Cluster pod, which destroys the nested cluster and everything in it
+	AllowRecreate bool `json:"allowRecreate,omitempty"`
+
+	// DeletionProtection blocks deleting the Cluster until it is set back to false
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// WaitForRollout keeps the Cluster from being ready until every Deployment, DaemonSet
+	// and StatefulSet in clusterYAML has rolled out
+	WaitForRollout bool `json:"waitForRollout,omitempty"`
+
+	// Workers is the number of worker nodes in the nested cluster, for the cluster types that support it.
+	// For k3s-native clusters each worker is a pod of its own, and for kwok clusters a fake node.
+	// For either, changing it doesn't recreate the Cluster pod.
+	// +kubebuilder:validation:Minimum=0
+	Workers *int32 `json:"workers,omitempty"`
+
+	// ControllerManager also runs kube-controller-manager in controlplane and kwok clusters,
+	// which is needed for service account tokens, garbage collection and workload controllers
+	ControllerManager bool `json:"controllerManager,omitempty"`
+
+	// RestartPolicy is what happens when the Cluster pod fails to bootstrap or become ready
+	// +kubebuilder:validation:Enum=Never;Recreate
+	RestartPolicy ClusterRestartPolicy `json:"restartPolicy,omitempty"`
+
+	// MaxRetries is how many times a failed Cluster pod is recreated before giving up
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// Tools pins the versions of the tools the Cluster pod downloads to create the nested cluster
+	// +optional
+	Tools *BootstrapTools `json:"tools,omitempty"`
+
+	// Kind configures kind clusters. spec.clusterSpec is still read when it isn't set.
+	// +optional
+	Kind *KindSpec `json:"kind,omitempty"`
+
+	// K3s configures k3s and k3s-native clusters. spec.workers is their agent count.
+	// +optional
+	K3s *K3sConfig `json:"k3s,omitempty"`
+}
+
+// BootstrapTools pins the tools Docker-in-Docker Cluster pods download. Unpinned tools are
+// downloaded at their latest version, except kubectl which then comes with the pod's image.
+type BootstrapTools struct {
+	// +optional
+	Kind *ToolVersion `json:"kind,omitempty"`
+
+	// K3d must be v5.3.0 or newer to read the k3d config kaas renders
+	// +optional
+	K3d *ToolVersion `json:"k3d,omitempty"`
+
+	// +optional
+	Kubectl *ToolVersion `json:"kubectl,omitempty"`
+
+	// +optional
+	Minikube *ToolVersion `json:"minikube,omitempty"`
+}
+
+// ToolVersion pins a release of a bootstrap tool
+type ToolVersion struct {
+	// Version is the release to download, such as v0.8.1
+	Version string `json:"version"`
+
+	// SHA256 is the hex SHA-256 checksum of the tool's linux-amd64 binary, checked inside the pod before it runs
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// KindSpec is the typed configuration of kind clusters, converted into a kind.x-k8s.io/v1alpha4 Cluster.
+// The API server address and port are always set by kaas, so they can't be configured.
+type KindSpec struct {
+	// Nodes are the nested cluster's nodes. Defaults to a single control-plane node.
+	// +optional
+	Nodes []KindNode `json:"nodes,omitempty"`
+
+	// Networking configures the nested cluster's network
+	// +optional
+	Networking *KindNetworking `json:"networking,omitempty"`
+
+	// FeatureGates are enabled or disabled on every Kubernetes component
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// KubeadmConfigPatches are merged into the kubeadm config of every node
+	// +optional
+	KubeadmConfigPatches []string `json:"kubeadmConfigPatches,omitempty"`
+
+	// ContainerdConfigPatches are merged into the containerd config of every node, in TOML
+	// +optional
+	ContainerdConfigPatches []string `json:"containerdConfigPatches,omitempty"`
+}
+
+// KindNode is a node of a kind cluster
+type KindNode struct {
+	// Role is either control-plane or worker
+	// +kubebuilder:validation:Enum=control-plane;worker
+	Role string `json:"role"`
+
+	// Image is ignored in favour of spec.image, and reported in status.overriddenFields when it differs
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ExtraMounts are directories of the Cluster pod mounted into the node
+	// +optional
+	ExtraMounts []KindMount `json:"extraMounts,omitempty"`
+
+	// KubeadmConfigPatches are merged into this node's kubeadm config
+	// +optional
+	KubeadmConfigPatches []string `json:"kubeadmConfigPatches,omitempty"`
+}
+
+// KindNetworking configures the network of a kind cluster
+type KindNetworking struct {
+	// IPFamily is either ipv4 or ipv6
+	// +kubebuilder:validation:Enum=ipv4;ipv6
+	// +optional
+	IPFamily string `json:"ipFamily,omitempty"`
+
+	// PodSubnet is the CIDR pod IPs are allocated from
+	// +optional
+	PodSubnet string `json:"podSubnet,omitempty"`
+
+	// ServiceSubnet is the CIDR service IPs are allocated from
+	// +optional
+	ServiceSubnet string `json:"serviceSubnet,omitempty"`
 
-	Memory *resource.Quantity `json:"memory"`
+	// DisableDefaultCNI leaves installing a CNI to clusterYAML
+	// +optional
+	DisableDefaultCNI bool `json:"disableDefaultCNI,omitempty"`
+}
+
+// KindMount mounts a directory of the Cluster pod into a kind node
+type KindMount struct {
+	// HostPath is the directory inside the Cluster pod
+	HostPath string `json:"hostPath"`
+
+	// ContainerPath is where it's mounted inside the node
+	ContainerPath string `json:"containerPath"`
+
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// +optional
+	SelinuxRelabel bool `json:"selinuxRelabel,omitempty"`
+
+	// +kubebuilder:validation:Enum=None;HostToContainer;Bidirectional
+	// +optional
+	Propagation string `json:"propagation,omitempty"`
+}
+
+// K3sComponent is a component k3s deploys by default
+// +kubebuilder:validation:Enum=coredns;servicelb;traefik;local-storage;metrics-server
+type K3sComponent string
+
+// K3sConfig is the typed configuration of k3s clusters, rendered into a k3d config file
+// for k3s clusters and into the k3s command lines for k3s-native clusters
+type K3sConfig struct {
+	// ServerArgs are extra flags for the k3s server, such as --kube-apiserver-arg=v=4
+	// +optional
+	ServerArgs []string `json:"serverArgs,omitempty"`
+
+	// AgentArgs are extra flags for the k3s agents
+	// +optional
+	AgentArgs []string `json:"agentArgs,omitempty"`
+
+	// Disable lists the packaged components k3s shouldn't deploy
+	// +optional
+	Disable []K3sComponent `json:"disable,omitempty"`
+
+	// Registries configures the image registries every node pulls from, like k3s' registries.yaml
+	// +optional
+	Registries *K3sRegistries `json:"registries,omitempty"`
+
+	// Volumes are extra directories of the Cluster pod mounted into every k3d node. k3s clusters only.
+	// +optional
+	Volumes []K3sVolume `json:"volumes,omitempty"`
+}
+
+// K3sRegistries is the typed form of k3s' registries.yaml. Credentials are left out on
+// purpose, they don't belong in a Cluster spec.
+type K3sRegistries struct {
+	// Mirrors maps a registry host, such as docker.io, to the endpoints to pull its images from
+	// +optional
+	Mirrors map[string]K3sRegistryMirror `json:"mirrors,omitempty"`
+
+	// Configs maps a registry host to its TLS settings
+	// +optional
+	Configs map[string]K3sRegistryConfig `json:"configs,omitempty"`
+}
+
+// K3sRegistryMirror lists the endpoints of a registry mirror
+type K3sRegistryMirror struct {
+	// Endpoints are http or https URLs, tried in order
+	Endpoints []string `json:"endpoint"`
+}
+
+// K3sRegistryConfig holds the TLS settings of a registry
+type K3sRegistryConfig struct {
+	// +optional
+	TLS *K3sRegistryTLS `json:"tls,omitempty"`
+}
+
+// K3sRegistryTLS holds the TLS settings of a registry
+type K3sRegistryTLS struct {
+	// InsecureSkipVerify disables verifying the registry's certificate
+	// +optional
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// K3sVolume mounts a directory of the Cluster pod into k3d nodes
+type K3sVolume struct {
+	// HostPath is the directory inside the Cluster pod
+	HostPath string `json:"hostPath"`
+
+	// MountPath is where it's mounted inside the nodes
+	MountPath string `json:"mountPath"`
+
+	// NodeFilters picks the nodes to mount it in, using k3d's node filters such as server:0 or agent:*. Defaults to all nodes.
+	// +optional
+	NodeFilters []string `json:"nodeFilters,omitempty"`
+}
+
+// ClusterPhase is a coarse summary of where a Cluster is in its lifecycle
+type ClusterPhase string
+
+const (
+	// ClusterPhasePending means the Cluster pod has not started running yet
+	ClusterPhasePending ClusterPhase = "Pending"
+	// ClusterPhaseBootstrapping means the nested cluster is being created inside the pod
+	ClusterPhaseBootstrapping ClusterPhase = "Bootstrapping"
+	// ClusterPhaseApplyingManifests means the clusterYAML manifests are being applied
+	ClusterPhaseApplyingManifests ClusterPhase = "ApplyingManifests"
+	// ClusterPhaseFetchingKubeconfig means the kubeconfigs are being copied out of the pod
+	ClusterPhaseFetchingKubeconfig ClusterPhase = "FetchingKubeconfig"
+	// ClusterPhaseReady means the nested cluster is up and its kubeconfigs are stored
+	ClusterPhaseReady ClusterPhase = "Ready"
+	// ClusterPhaseDegraded means the nested cluster was ready but is failing its health checks
+	ClusterPhaseDegraded ClusterPhase = "Degraded"
+	// ClusterPhaseFailed means the Cluster pod failed and will not become ready
+	ClusterPhaseFailed ClusterPhase = "Failed"
+	// ClusterPhaseTerminating means the nested cluster is being torn down before the Cluster is deleted
+	ClusterPhaseTerminating ClusterPhase = "Terminating"
+)
+
+// ClusterConditionType is the type of a ClusterCondition
+// +kubebuilder:validation:Pattern=`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$`
+// +kubebuilder:validation:MaxLength=316
+type ClusterConditionType string
+
+const (
+	// ClusterPodScheduled means the Cluster pod has been scheduled to a node
+	ClusterPodScheduled ClusterConditionType = "PodScheduled"
+	// ClusterBootstrapped means the nested cluster has been created inside the pod
+	ClusterBootstrapped ClusterConditionType = "Bootstrapped"
+	// ClusterManifestsApplied means every clusterYAML manifest has been applied
+	ClusterManifestsApplied ClusterConditionType = "ManifestsApplied"
+	// ClusterKubeconfigReady means the kubeconfig Secret is up to date
+	ClusterKubeconfigReady ClusterConditionType = "KubeconfigReady"
+	// ClusterReady means the nested cluster is ready to be used
+	ClusterReady ClusterConditionType = "Ready"
+	// ClusterHealthy means the nested API server, nodes and kube-system pods passed their last health check
+	ClusterHealthy ClusterConditionType = "Healthy"
+	// ClusterDeletionBlocked means the Cluster was deleted but deletion protection is holding it
+	ClusterDeletionBlocked ClusterConditionType = "DeletionBlocked"
+)
+
+const (
+	// DeletionProtectionFinalizer holds on to Clusters with spec.deletionProtection set
+	DeletionProtectionFinalizer = "honk.honk.ci/deletion-protection"
+	// TeardownFinalizer holds on to Clusters until the nested cluster has been cleanly deleted
+	TeardownFinalizer = "honk.honk.ci/teardown"
+	// TeardownLogsLabel marks the ConfigMaps holding logs exported by a teardown
+	TeardownLogsLabel = "honk.honk.ci/teardown-logs"
+	// DeleteAfterAnnotation is when the controller may delete a ConfigMap that outlives its Cluster
+	DeleteAfterAnnotation = "honk.honk.ci/delete-after"
+
+	// DefaultTeardownTimeout is how long a teardown may take when the KaasConfig doesn't say
+	DefaultTeardownTimeout = 2 * time.Minute
+	// DefaultTeardownLogsTTL is how long teardown logs are kept when the KaasConfig doesn't say
+	DefaultTeardownLogsTTL = 7 * 24 * time.Hour
+	// DefaultProvisioningTimeout is how long a Cluster may take to become ready when the KaasConfig doesn't say
+	DefaultProvisioningTimeout = 20 * time.Minute
+	// DefaultRequeueInterval is the first requeue interval while a Cluster is provisioning
+	DefaultRequeueInterval = 5 * time.Second
+	// DefaultMaxRequeueInterval caps the backoff between checks on a provisioning Cluster
+	DefaultMaxRequeueInterval = time.Minute
+	// DefaultHealthCheckInterval is how often a ready Cluster is checked on when the KaasConfig doesn't say
+	DefaultHealthCheckInterval = time.Minute
+	// DefaultMaxRetries is how many times a failed Cluster pod is recreated when spec.maxRetries isn't set
+	DefaultMaxRetries = 3
+)
+
+// ClusterCondition describes the state of one aspect of a Cluster at a point in time.
+// It mirrors metav1.Condition, which the vendored apimachinery predates, so that the
+// two can be swapped once the module moves to v0.19 or later.
+type ClusterCondition struct {
+	// Type of the condition, in CamelCase or in foo.example.com/CamelCase
+	// +kubebuilder:validation:Required
+	Type ClusterConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status v1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the .metadata.generation the condition was computed from
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is when the condition last changed from one status to another
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Format=date-time
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Reason is a programmatic, CamelCase identifier for the last transition
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`
+	Reason string `json:"reason"`
+
+	// Message is a human readable message with details about the transition, which may be empty
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=32768
+	Message string `json:"message"`
 }
 
 // ClusterStatus defines the observed state of Cluster
@@ -65,12 +485,74 @@ type ClusterStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	Ready          bool   `json:"ready"`
 	LoadBalancerIP string `json:"loadBalancerIP"`
+
+	Phase ClusterPhase `json:"phase,omitempty"`
+
+	// ObservedGeneration is the most recent .metadata.generation the controller has acted on
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+
+	// ExpiresAt is when the controller will delete the Cluster
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// ProvisioningStartedAt is when the current Cluster pod was created
+	ProvisioningStartedAt *metav1.Time `json:"provisioningStartedAt,omitempty"`
+	// ReadyAt is when the Cluster last became ready
+	ReadyAt *metav1.Time `json:"readyAt,omitempty"`
+
+	// KubernetesVersion is the version reported by the nested API server
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	// NodeCount is the number of nodes in the nested cluster
+	NodeCount int32 `json:"nodeCount,omitempty"`
+	// APIEndpoint is the host:port the nested API server is reachable on
+	APIEndpoint string `json:"apiEndpoint,omitempty"`
+	// KubeconfigSecret is the name of the Secret holding the nested cluster's kubeconfigs
+	KubeconfigSecret string `json:"kubeconfigSecret,omitempty"`
+
+	// Attempts is how many times a failed Cluster pod has been recreated since the spec last changed it
+	Attempts int32 `json:"attempts,omitempty"`
+	// LastError is why the Cluster pod last failed
+	LastError string `json:"lastError,omitempty"`
+	// BootstrapLogs is the name of the ConfigMap holding the logs captured from the last failed Cluster pod
+	BootstrapLogs string `json:"bootstrapLogs,omitempty"`
+	// LogExportStartedAt is when the controller started exporting the failed Cluster pod's logs in the background
+	LogExportStartedAt *metav1.Time `json:"logExportStartedAt,omitempty"`
+	// ToolVersions are the versions of the bootstrap tools the Cluster pod ran, keyed by tool
+	ToolVersions map[string]string `json:"toolVersions,omitempty"`
+	// OverriddenFields lists the parts of the Cluster's config the controller replaced with its own values
+	OverriddenFields []string `json:"overriddenFields,omitempty"`
+	// Defaults are the KaasConfig defaults the Cluster was first reconciled with
+	Defaults *ClusterDefaults `json:"defaults,omitempty"`
+}
+
+// ClusterDefaults snapshots the KaasConfig defaults a Cluster relies on for fields its spec leaves
+// empty, so that changing the KaasConfig only affects new Clusters instead of recreating existing ones
+type ClusterDefaults struct {
+	// Image is the node image used when spec.image is empty
+	Image string `json:"image,omitempty"`
+	// ServiceType is how the nested API server is exposed when spec.serviceType is empty
+	ServiceType v1.ServiceType `json:"serviceType,omitempty"`
+	// Port is the port the Cluster's Service exposes the nested API server on
+	Port *v1.ServicePort `json:"port,omitempty"`
+	// Tools are the bootstrap tool pins used when spec.tools doesn't pin a tool
+	Tools *BootstrapTools `json:"tools,omitempty"`
 }
 
 // Cluster is the Schema for the clusters API
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Flavor",type=string,JSONPath=`.spec.clusterType`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.kubernetesVersion`
+// +kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.apiEndpoint`,priority=1
+// +kubebuilder:printcolumn:name="Kubeconfig",type=string,JSONPath=`.status.kubeconfigSecret`,priority=1
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expiresAt`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -79,7 +561,8 @@ type Cluster struct {
 	Spec   ClusterSpec   `json:"spec,omitempty"`
 	Status ClusterStatus `json:"status,omitempty"`
 
-	KaasConfig *KaasConfig `json:"kaasConfig,omitempty"`
+	// KaasConfig is only held in memory while reconciling and is never persisted
+	KaasConfig *KaasConfig `json:"-"`
 }
 
 // +kubebuilder:object:root=true
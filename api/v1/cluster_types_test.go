@@ -0,0 +1,50 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestClusterKaasConfigIsNotPersisted(t *testing.T) {
+	c := Cluster{}
+	c.Name = "test"
+	c.Spec.ClusterType = KindCluster
+	c = c.SetConfig(&KaasConfig{DefaultServiceType: v1.ServiceTypeNodePort})
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, leaked := range []string{"kaasConfig", "KaasConfig", "defaultServiceType"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("json.Marshal() = %s, contains %q", data, leaked)
+		}
+	}
+
+	var got Cluster
+	if err := json.Unmarshal([]byte(`{"metadata":{"name":"test"},"kaasConfig":{"defaultServiceType":"NodePort"},"KaasConfig":{"defaultServiceType":"NodePort"}}`), &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if got.KaasConfig != nil {
+		t.Errorf("json.Unmarshal() KaasConfig = %+v, want nil", got.KaasConfig)
+	}
+}
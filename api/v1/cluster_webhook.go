@@ -0,0 +1,299 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var clusterlog = logf.Log.WithName("cluster-resource")
+
+var (
+	// webhookClient reads the KaasConfig when defaulting and validating Clusters
+	webhookClient client.Reader
+	// webhookRecorder tells users about the consequences of updates it lets through, since
+	// this version of controller-runtime can't return admission warnings
+	webhookRecorder record.EventRecorder
+)
+
+// SetupWebhookWithManager registers the Cluster webhooks with the manager
+func (r *Cluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetAPIReader()
+	webhookRecorder = mgr.GetEventRecorderFor("cluster-webhook")
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-honk-honk-ci-v1-cluster,mutating=true,failurePolicy=fail,groups=honk.honk.ci,resources=clusters,verbs=create;update,versions=v1,name=mcluster.honk.ci
+
+var _ webhook.Defaulter = &Cluster{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+// Only new Clusters are defaulted: filling in an existing Cluster from the current
+// KaasConfig would change its pod behind the user's back.
+func (r *Cluster) Default() {
+	clusterlog.Info("default", "name", r.Name)
+
+	// The API server only sets the creation timestamp after mutating admission
+	if !r.CreationTimestamp.IsZero() {
+		return
+	}
+
+	r.loadKaasConfig()
+	r.SetDefaults()
+}
+
+// loadKaasConfig reads the KaasConfig into the Cluster unless it already has one
+func (r *Cluster) loadKaasConfig() {
+	if r.KaasConfig != nil || webhookClient == nil {
+		return
+	}
+
+	config := &KaasConfig{}
+	err := webhookClient.Get(context.Background(), types.NamespacedName{Name: KaasConfigName, Namespace: KaasConfigNamespace}, config)
+	if err != nil {
+		// Same as the controller, the config only overrides the built-in defaults
+		clusterlog.Info("unable to get KaasConfig", "error", err.Error())
+		return
+	}
+	r.KaasConfig = config
+}
+
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-honk-honk-ci-v1-cluster,mutating=false,failurePolicy=fail,groups=honk.honk.ci,resources=clusters,versions=v1,name=vcluster.honk.ci
+
+var _ webhook.Validator = &Cluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Cluster) ValidateCreate() error {
+	clusterlog.Info("validate create", "name", r.Name)
+
+	return r.validateCluster()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Cluster) ValidateUpdate(old runtime.Object) error {
+	clusterlog.Info("validate update", "name", r.Name)
+
+	// Metadata-only updates, like the controller managing finalizers, must always go through
+	oldCluster := old.(*Cluster)
+	if reflect.DeepEqual(r.Spec, oldCluster.Spec) {
+		return nil
+	}
+
+	// Both sides need the same defaults, otherwise unchanged fields look changed
+	r.loadKaasConfig()
+	oldCluster.KaasConfig = r.KaasConfig
+
+	allErrs := r.validateClusterSpec()
+	// Clusters created before the policy was enabled can still be edited, as long as the tools are left alone
+	if !reflect.DeepEqual(r.Spec.Tools, oldCluster.Spec.Tools) {
+		allErrs = append(allErrs, r.validatePinnedTools()...)
+	}
+	updateErrs, impact := r.validateClusterUpdate(oldCluster)
+	allErrs = append(allErrs, updateErrs...)
+	if len(allErrs) == 0 {
+		if impact != "" {
+			clusterlog.Info("warning: "+impact, "name", r.Name)
+			if webhookRecorder != nil {
+				webhookRecorder.Event(r, v1.EventTypeWarning, "RecreateAllowed", fmt.Sprintf("spec.allowRecreate is set and %s", impact))
+			}
+		}
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "Cluster"},
+		r.Name, allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Cluster) ValidateDelete() error {
+	clusterlog.Info("validate delete", "name", r.Name)
+
+	if !r.Spec.DeletionProtection {
+		return nil
+	}
+
+	return apierrors.NewForbidden(
+		schema.GroupResource{Group: GroupVersion.Group, Resource: "clusters"},
+		r.Name, fmt.Errorf("deletion protection is enabled, set spec.deletionProtection to false first"))
+}
+
+func (r *Cluster) validateCluster() error {
+	allErrs := append(r.validateClusterSpec(), r.validatePinnedTools()...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "Cluster"},
+		r.Name, allErrs)
+}
+
+// validatePinnedTools applies the KaasConfig's requirePinnedTools policy
+func (r *Cluster) validatePinnedTools() field.ErrorList {
+	r.loadKaasConfig()
+	if unpinned := r.UnpinnedTools(); r.RequiresPinnedTools() && len(unpinned) > 0 {
+		return field.ErrorList{field.Forbidden(field.NewPath("spec", "tools"), fmt.Sprintf("the KaasConfig requires a version and sha256 for every bootstrap tool, missing: %s", strings.Join(unpinned, ", ")))}
+	}
+	return nil
+}
+
+func (r *Cluster) validateClusterSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if provisioner, ok := r.Provisioner(); ok {
+		allErrs = append(allErrs, provisioner.Validate(*r)...)
+	} else {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("clusterType"), r.Spec.ClusterType, ClusterTypes()))
+	}
+
+	if r.Spec.CPU == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("cpu"), "a CPU quantity such as 500m is required"))
+	} else if r.Spec.CPU.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("cpu"), r.Spec.CPU.String(), "must be greater than zero"))
+	}
+
+	if r.Spec.Memory == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("memory"), "a memory quantity such as 1Gi is required"))
+	} else if r.Spec.Memory.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("memory"), r.Spec.Memory.String(), "must be greater than zero"))
+	}
+
+	switch r.Spec.ServiceType {
+	case "", v1.ServiceTypeClusterIP, v1.ServiceTypeNodePort, v1.ServiceTypeLoadBalancer:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("serviceType"), r.Spec.ServiceType, []string{string(v1.ServiceTypeClusterIP), string(v1.ServiceTypeNodePort), string(v1.ServiceTypeLoadBalancer)}))
+	}
+
+	switch r.Spec.RestartPolicy {
+	case "", RestartPolicyNever, RestartPolicyRecreate:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("restartPolicy"), r.Spec.RestartPolicy, []string{string(RestartPolicyNever), string(RestartPolicyRecreate)}))
+	}
+
+	if r.Spec.Workers != nil && *r.Spec.Workers < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("workers"), *r.Spec.Workers, "must not be negative"))
+	}
+
+	if r.Spec.Kind != nil && r.Spec.ClusterType != KindCluster {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("kind"), fmt.Sprintf("only applies to %s clusters", KindCluster)))
+	}
+
+	if r.Spec.K3s != nil && r.Spec.ClusterType != K3sCluster && r.Spec.ClusterType != K3sNativeCluster {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("k3s"), fmt.Sprintf("only applies to %s and %s clusters", K3sCluster, K3sNativeCluster)))
+	}
+
+	if r.Spec.Tools != nil {
+		allErrs = append(allErrs, r.Spec.Tools.validate(specPath.Child("tools"))...)
+	}
+
+	if r.Spec.MaxRetries != nil && *r.Spec.MaxRetries < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxRetries"), *r.Spec.MaxRetries, "must not be negative"))
+	}
+
+	for i, manifest := range r.Spec.ClusterYAML {
+		if err := validateManifest(manifest); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("clusterYAML").Index(i), "", err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+// validateClusterUpdate blocks changes that would silently destroy the nested cluster.
+// It also returns what a destructive update spec.allowRecreate lets through will do.
+func (r *Cluster) validateClusterUpdate(old *Cluster) (field.ErrorList, string) {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if r.Spec.ClusterType != old.Spec.ClusterType {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("clusterType"), r.Spec.ClusterType, "field is immutable"))
+		return allErrs, ""
+	}
+
+	// The controller creates the Service once and never changes its type
+	if r.ServiceType() != old.ServiceType() {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("serviceType"), r.Spec.ServiceType, "field is immutable"))
+	}
+
+	changes := r.RecreateChanges(*old)
+	if len(changes) == 0 {
+		return allErrs, ""
+	}
+
+	impact := fmt.Sprintf("this update changes the Cluster pod's %s, so the pod will be recreated and the nested cluster and everything running in it destroyed", strings.Join(changes, ", "))
+	if !r.Spec.AllowRecreate {
+		allErrs = append(allErrs, field.Forbidden(specPath, impact+"; set spec.allowRecreate to true to proceed"))
+		return allErrs, ""
+	}
+
+	return allErrs, impact
+}
+
+// validateManifest checks that every document in a clusterYAML entry parses
+// and looks like a Kubernetes object, so kubectl apply has a chance of working
+func validateManifest(manifest string) error {
+	decoder := yaml.NewDecoder(bytes.NewBufferString(manifest))
+	objects := 0
+	for doc := 1; ; doc++ {
+		var object map[string]interface{}
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("document %d is not valid YAML: %s", doc, err.Error())
+		}
+		if object == nil {
+			continue
+		}
+
+		for _, key := range []string{"apiVersion", "kind"} {
+			if value, ok := object[key].(string); !ok || value == "" {
+				return fmt.Errorf("document %d is not a Kubernetes object: missing %s", doc, key)
+			}
+		}
+		objects++
+	}
+
+	if objects == 0 {
+		return fmt.Errorf("must contain at least one Kubernetes object")
+	}
+	return nil
+}
@@ -0,0 +1,481 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "single object",
+			manifest: "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test\n",
+		},
+		{
+			name:     "several objects",
+			manifest: "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: b\n",
+		},
+		{
+			name:     "empty documents are skipped",
+			manifest: "---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: a\n---\n",
+		},
+		{
+			name:     "empty",
+			manifest: "",
+			wantErr:  "at least one Kubernetes object",
+		},
+		{
+			name:     "only empty documents",
+			manifest: "---\n---\n",
+			wantErr:  "at least one Kubernetes object",
+		},
+		{
+			name:     "invalid YAML",
+			manifest: "apiVersion: v1\nkind: [Namespace\n",
+			wantErr:  "document 1 is not valid YAML",
+		},
+		{
+			name:     "missing kind",
+			manifest: "apiVersion: v1\nmetadata:\n  name: a\n",
+			wantErr:  "document 1 is not a Kubernetes object: missing kind",
+		},
+		{
+			name:     "missing apiVersion in a later document",
+			manifest: "apiVersion: v1\nkind: Namespace\n---\nkind: Namespace\n",
+			wantErr:  "document 2 is not a Kubernetes object: missing apiVersion",
+		},
+		{
+			name:     "kind isn't a string",
+			manifest: "apiVersion: v1\nkind:\n  name: Namespace\n",
+			wantErr:  "missing kind",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateManifest(tt.manifest)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateManifest() = %v, want no error", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validateManifest() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// validCluster is a kind Cluster as the defaulting webhook leaves it
+func validCluster() *Cluster {
+	cpu := resource.MustParse("500m")
+	memory := resource.MustParse("1Gi")
+	c := &Cluster{}
+	c.Name, c.Namespace = "test", "default"
+	c.Spec.ClusterType = KindCluster
+	c.Spec.CPU = &cpu
+	c.Spec.Memory = &memory
+	return c
+}
+
+func TestValidateClusterSpec(t *testing.T) {
+	negative := int32(-1)
+
+	tests := []struct {
+		name   string
+		mutate func(c *Cluster)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(c *Cluster) {},
+		},
+		{
+			name:   "unknown cluster type",
+			mutate: func(c *Cluster) { c.Spec.ClusterType = "kubeadm" },
+			want:   []string{"spec.clusterType"},
+		},
+		{
+			name: "missing resources",
+			mutate: func(c *Cluster) {
+				c.Spec.CPU = nil
+				c.Spec.Memory = nil
+			},
+			want: []string{"spec.cpu", "spec.memory"},
+		},
+		{
+			name: "zero resources",
+			mutate: func(c *Cluster) {
+				zero := resource.MustParse("0")
+				c.Spec.CPU = &zero
+				c.Spec.Memory = &zero
+			},
+			want: []string{"spec.cpu", "spec.memory"},
+		},
+		{
+			name:   "unsupported service type",
+			mutate: func(c *Cluster) { c.Spec.ServiceType = "ExternalName" },
+			want:   []string{"spec.serviceType"},
+		},
+		{
+			name:   "unsupported restart policy",
+			mutate: func(c *Cluster) { c.Spec.RestartPolicy = "Always" },
+			want:   []string{"spec.restartPolicy"},
+		},
+		{
+			name: "negative counts",
+			mutate: func(c *Cluster) {
+				c.Spec.Workers = &negative
+				c.Spec.MaxRetries = &negative
+			},
+			want: []string{"spec.workers", "spec.maxRetries"},
+		},
+		{
+			name: "kind config on a k3s cluster",
+			mutate: func(c *Cluster) {
+				c.Spec.ClusterType = K3sCluster
+				c.Spec.Kind = &KindSpec{}
+			},
+			want: []string{"spec.kind"},
+		},
+		{
+			name: "k3s config on a kind cluster",
+			mutate: func(c *Cluster) {
+				c.Spec.K3s = &K3sConfig{}
+			},
+			want: []string{"spec.k3s"},
+		},
+		{
+			name:   "invalid manifest",
+			mutate: func(c *Cluster) { c.Spec.ClusterYAML = []string{"kind: Namespace"} },
+			want:   []string{"spec.clusterYAML[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validCluster()
+			tt.mutate(c)
+
+			var got []string
+			for _, err := range c.validateClusterSpec() {
+				got = append(got, err.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateClusterSpec() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cpu := resource.MustParse("2")
+	memory := resource.MustParse("4Gi")
+	config := &KaasConfig{
+		DefaultServiceType: corev1.ServiceTypeLoadBalancer,
+		DefaultImages:      map[ClusterType]string{KindCluster: "kindest/node:v1.17.0"},
+		DefaultCPU:         &cpu,
+		DefaultMemory:      &memory,
+	}
+
+	t.Run("new Clusters get the KaasConfig defaults", func(t *testing.T) {
+		c := &Cluster{}
+		c.Spec.ClusterType = KindCluster
+		c.KaasConfig = config
+		c.Default()
+
+		if c.Spec.Image != "kindest/node:v1.17.0" {
+			t.Errorf("spec.image = %q, want the KaasConfig default", c.Spec.Image)
+		}
+		if c.Spec.CPU == nil || c.Spec.CPU.Cmp(cpu) != 0 || c.Spec.Memory == nil || c.Spec.Memory.Cmp(memory) != 0 {
+			t.Errorf("spec.cpu, spec.memory = %v, %v, want %v, %v", c.Spec.CPU, c.Spec.Memory, cpu.String(), memory.String())
+		}
+		if c.Spec.ServiceType != corev1.ServiceTypeLoadBalancer {
+			t.Errorf("spec.serviceType = %q, want %q", c.Spec.ServiceType, corev1.ServiceTypeLoadBalancer)
+		}
+		if c.Spec.RestartPolicy != RestartPolicyNever {
+			t.Errorf("spec.restartPolicy = %q, want %q", c.Spec.RestartPolicy, RestartPolicyNever)
+		}
+	})
+
+	t.Run("existing Clusters are left alone", func(t *testing.T) {
+		c := &Cluster{}
+		c.CreationTimestamp = metav1.Now()
+		c.Spec.ClusterType = KindCluster
+		c.KaasConfig = config
+		want := c.Spec.DeepCopy()
+		c.Default()
+
+		if !reflect.DeepEqual(&c.Spec, want) {
+			t.Errorf("Default() changed the spec of an existing Cluster to %+v", c.Spec)
+		}
+	})
+}
+
+func TestToolDefaultsOnUpdate(t *testing.T) {
+	kubectl := &ToolVersion{Version: "v1.18.0", SHA256: strings.Repeat("a", 64)}
+	kind := &ToolVersion{Version: "v0.8.1", SHA256: strings.Repeat("b", 64)}
+	config := &KaasConfig{
+		Tools:              &BootstrapTools{Kind: kind, Kubectl: kubectl},
+		RequirePinnedTools: true,
+	}
+
+	t.Run("new Clusters get the KaasConfig pins", func(t *testing.T) {
+		c := validCluster()
+		c.KaasConfig = config
+		c.Default()
+
+		if !reflect.DeepEqual(c.Spec.Tools, config.Tools) {
+			t.Errorf("spec.tools = %+v, want the KaasConfig's %+v", c.Spec.Tools, config.Tools)
+		}
+	})
+
+	t.Run("new Clusters without pins are refused", func(t *testing.T) {
+		c := validCluster()
+		c.KaasConfig = &KaasConfig{RequirePinnedTools: true}
+
+		if err := c.ValidateCreate(); !apierrors.IsInvalid(err) {
+			t.Errorf("ValidateCreate() = %v, want spec.tools to be invalid", err)
+		}
+	})
+
+	// An existing Cluster created before the KaasConfig pinned anything, as the API server
+	// sends it through Default and then ValidateUpdate
+	tests := []struct {
+		name   string
+		update func(c *Cluster)
+	}{
+		{
+			name:   "finalizer added",
+			update: func(c *Cluster) { c.Finalizers = append(c.Finalizers, TeardownFinalizer) },
+		},
+		{
+			name: "finalizer removed while terminating",
+			update: func(c *Cluster) {
+				now := metav1.Now()
+				c.DeletionTimestamp = &now
+				c.Finalizers = nil
+			},
+		},
+		{
+			name:   "labels changed",
+			update: func(c *Cluster) { c.Labels = map[string]string{"team": "a"} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := validCluster()
+			old.CreationTimestamp = metav1.Now()
+			old.Finalizers = []string{DeletionProtectionFinalizer}
+			c := old.DeepCopy()
+			c.KaasConfig = config
+			tt.update(c)
+
+			c.Default()
+			if !reflect.DeepEqual(c.Spec, old.Spec) {
+				t.Errorf("Default() changed the spec to %+v", c.Spec)
+			}
+			if err := c.ValidateUpdate(old); err != nil {
+				t.Errorf("ValidateUpdate() = %v, want no error", err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateRecordsAllowedRecreate(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	webhookRecorder = recorder
+	defer func() { webhookRecorder = nil }()
+
+	old := validCluster()
+	c := old.DeepCopy()
+	c.Spec.Image = "kindest/node:v1.17.0"
+	c.Spec.AllowRecreate = true
+	if err := c.ValidateUpdate(old); err != nil {
+		t.Fatalf("ValidateUpdate() = %v, want no error", err)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning RecreateAllowed ") || !strings.Contains(event, "command") {
+			t.Errorf("recorded %q, want a RecreateAllowed warning about the pod command", event)
+		}
+	default:
+		t.Errorf("no Event recorded for a destructive update")
+	}
+
+	// Nothing is recorded for updates that are rejected or harmless
+	c.Spec.AllowRecreate = false
+	if err := c.ValidateUpdate(old); err == nil {
+		t.Fatalf("ValidateUpdate() = nil, want the image change rejected")
+	}
+	c = old.DeepCopy()
+	c.Spec.DeletionProtection = true
+	if err := c.ValidateUpdate(old); err != nil {
+		t.Fatalf("ValidateUpdate() = %v, want no error", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("recorded %q, want no Events", <-recorder.Events)
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *KaasConfig
+		old    func(c *Cluster)
+		new    func(c *Cluster)
+		want   []string
+	}{
+		{
+			name: "metadata-only update",
+			new:  func(c *Cluster) { c.Labels = map[string]string{"team": "a"} },
+		},
+		{
+			name: "update that doesn't touch the pod",
+			new:  func(c *Cluster) { c.Spec.DeletionProtection = true },
+		},
+		{
+			name:   "pre-webhook Cluster with a KaasConfig default image",
+			config: &KaasConfig{DefaultImages: map[ClusterType]string{KindCluster: "kindest/node:v1.17.0"}},
+			new:    func(c *Cluster) { c.Spec.DeletionProtection = true },
+		},
+		{
+			name:   "unpinned Cluster updated after tools had to be pinned",
+			config: &KaasConfig{RequirePinnedTools: true},
+			new:    func(c *Cluster) { c.Spec.DeletionProtection = true },
+		},
+		{
+			name:   "tools changed but still unpinned",
+			config: &KaasConfig{RequirePinnedTools: true},
+			new: func(c *Cluster) {
+				c.Spec.Tools = &BootstrapTools{Kind: &ToolVersion{Version: "v0.8.1"}}
+				c.Spec.AllowRecreate = true
+			},
+			want: []string{"spec.tools"},
+		},
+		{
+			name: "image change",
+			new:  func(c *Cluster) { c.Spec.Image = "kindest/node:v1.17.0" },
+			want: []string{"spec"},
+		},
+		{
+			name: "image change with allowRecreate",
+			new: func(c *Cluster) {
+				c.Spec.Image = "kindest/node:v1.17.0"
+				c.Spec.AllowRecreate = true
+			},
+		},
+		{
+			name: "kwok workers change",
+			old: func(c *Cluster) {
+				c.Spec.ClusterType = KwokCluster
+				c.Spec.Workers = int32Ptr(2)
+			},
+			new: func(c *Cluster) { c.Spec.Workers = int32Ptr(10) },
+		},
+		{
+			name: "kwok controllerManager change",
+			old:  func(c *Cluster) { c.Spec.ClusterType = KwokCluster },
+			new:  func(c *Cluster) { c.Spec.ControllerManager = true },
+			want: []string{"spec"},
+		},
+		{
+			name: "clusterType change",
+			new:  func(c *Cluster) { c.Spec.ClusterType = K3sCluster },
+			want: []string{"spec.clusterType"},
+		},
+		{
+			name: "serviceType change",
+			new:  func(c *Cluster) { c.Spec.ServiceType = corev1.ServiceTypeLoadBalancer },
+			want: []string{"spec.serviceType"},
+		},
+		{
+			name: "serviceType set to the effective default",
+			old:  func(c *Cluster) { c.Spec.ServiceType = "" },
+			new:  func(c *Cluster) { c.Spec.ServiceType = corev1.ServiceTypeNodePort },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := validCluster()
+			old.Spec.ServiceType = corev1.ServiceTypeNodePort
+			if tt.old != nil {
+				tt.old(old)
+			}
+			c := old.DeepCopy()
+			c.KaasConfig = tt.config
+			tt.new(c)
+
+			var got []string
+			if err := c.ValidateUpdate(old); err != nil {
+				statusErr, ok := err.(*apierrors.StatusError)
+				if !ok {
+					t.Fatalf("ValidateUpdate() = %v, want a StatusError", err)
+				}
+				for _, cause := range statusErr.ErrStatus.Details.Causes {
+					got = append(got, cause.Field)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateUpdate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDelete(t *testing.T) {
+	tests := []struct {
+		name               string
+		deletionProtection bool
+		wantForbidden      bool
+	}{
+		{
+			name: "unprotected",
+		},
+		{
+			name:               "protected",
+			deletionProtection: true,
+			wantForbidden:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validCluster()
+			c.Spec.DeletionProtection = tt.deletionProtection
+
+			err := c.ValidateDelete()
+			if got := apierrors.IsForbidden(err); got != tt.wantForbidden || (err != nil && !got) {
+				t.Errorf("ValidateDelete() = %v, want forbidden %v", err, tt.wantForbidden)
+			}
+		})
+	}
+}
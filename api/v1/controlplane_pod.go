@@ -0,0 +1,308 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// pkiDir is where the bootstrap Secret is mounted in control plane pods
+	pkiDir = "/etc/kaas/pki"
+	// controlPlaneKubeconfigDir is where the bootstrap container keeps the nested cluster's kubeconfigs
+	controlPlaneKubeconfigDir = "/tmp/kube"
+
+	kubectlImage = "docker.io/bitnami/kubectl:1.18.0"
+)
+
+// etcdVersions are the etcd versions kubeadm ships with, by the first Kubernetes minor version
+// using them. Older Kubernetes versions get the oldest etcd here.
+var etcdVersions = []struct {
+	minor   int
+	version string
+}{
+	{16, "3.3.15-0"},
+	{17, "3.4.3-0"},
+	{19, "3.4.13-0"},
+	{22, "3.5.0-0"},
+	{23, "3.5.1-0"},
+	{24, "3.5.3-0"},
+	{25, "3.5.4-0"},
+	{26, "3.5.6-0"},
+	{27, "3.5.7-0"},
+	{28, "3.5.9-0"},
+	{29, "3.5.10-0"},
+	{30, "3.5.12-0"},
+}
+
+// kubernetesMinor returns the minor version of a Kubernetes version such as v1.18.0
+func kubernetesMinor(version string) (int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	return minor, err == nil
+}
+
+// etcdImage returns the etcd image kubeadm would run with a Kubernetes version, or the newest
+// one if the version can't be parsed
+func etcdImage(version string) string {
+	etcd := etcdVersions[len(etcdVersions)-1].version
+	if minor, ok := kubernetesMinor(version); ok {
+		etcd = etcdVersions[0].version
+		for _, v := range etcdVersions {
+			if v.minor <= minor {
+				etcd = v.version
+			}
+		}
+	}
+	return "k8s.gcr.io/etcd:" + etcd
+}
+
+// componentRequests reserves resources for a control plane component without limiting it
+func componentRequests(cpu, memory string) v1.ResourceRequirements {
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(memory),
+		},
+	}
+}
+
+// serviceAccountTokenSecret asks the token controller for a long-lived kind-user token. Service
+// accounts stopped getting one of their own in 1.24, but explicitly created ones are still filled in.
+const serviceAccountTokenSecret = `{"apiVersion":"v1","kind":"Secret","type":"kubernetes.io/service-account-token",` +
+	`"metadata":{"name":"kind-user-token","namespace":"default","annotations":{"kubernetes.io/service-account.name":"kind-user"}}}`
+
+// serviceAccountKubeconfig does what add_sa.sh does for Docker-in-Docker clusters, without
+// needing jq: it writes a kubeconfig for system:serviceaccount:default:kind-user, which has no RBAC.
+// Before 1.24 the token comes from the service account's own Secret, from then on (and for
+// versions it can't parse) from a token Secret created for it.
+func serviceAccountKubeconfig(version string) string {
+	steps := []string{"kubectl -n default create serviceaccount kind-user"}
+	if minor, ok := kubernetesMinor(version); ok && minor < 24 {
+		steps = append(steps, "until SECRET=$(kubectl -n default get serviceaccount kind-user -o jsonpath='{.secrets[0].name}') && [ -n \"$SECRET\" ]; do sleep 1; done")
+	} else {
+		steps = append(steps,
+			fmt.Sprintf("echo '%s' | kubectl create -f - >/dev/null", serviceAccountTokenSecret),
+			"SECRET=kind-user-token",
+		)
+	}
+	return strings.Join(append(steps,
+		"until TOKEN=$(kubectl -n default get secret \"$SECRET\" -o jsonpath='{.data.token}') && [ -n \"$TOKEN\" ]; do sleep 1; done",
+		"TOKEN=$(echo \"$TOKEN\" | base64 -d)",
+		fmt.Sprintf("cp %s/config %s/k8s-kind-user-default-conf", controlPlaneKubeconfigDir, controlPlaneKubeconfigDir),
+		fmt.Sprintf("export KUBECONFIG=%s/k8s-kind-user-default-conf", controlPlaneKubeconfigDir),
+		"kubectl config unset users.kubernetes-admin >/dev/null",
+		"kubectl config set-credentials kind-user --token=\"$TOKEN\" >/dev/null",
+		"kubectl config set-context --current --user=kind-user >/dev/null",
+		"unset KUBECONFIG",
+	), " && ")
+}
+
+// controlPlaneKubeconfigPaths are where the bootstrap container of a control plane pod leaves the kubeconfigs
+func controlPlaneKubeconfigPaths(serviceAccount bool) map[string]string {
+	paths := map[string]string{
+		"root-config": controlPlaneKubeconfigDir + "/config",
+	}
+	if serviceAccount {
+		paths["default-config"] = controlPlaneKubeconfigDir + "/k8s-kind-user-default-conf"
+	}
+	return paths
+}
+
+// bootstrapContainer is the primary container of pods that run the nested cluster as plain
+// containers. It runs prepare to get an admin kubeconfig, waits for the API server, runs setup,
+// writes the kubeconfigs and applies the clusterYAML manifests, dropping the same progress
+// markers as Docker-in-Docker pods.
+func (c Cluster) bootstrapContainer(prepare, setup, readinessCheck string, serviceAccount bool, mounts ...v1.VolumeMount) v1.Container {
+	steps := []string{
+		fmt.Sprintf("mkdir -p %s %s", progressDir, controlPlaneKubeconfigDir),
+	}
+	if c.Spec.WaitForRollout {
+		steps = append(steps, fmt.Sprintf("touch %s/%s", progressDir, waitForRolloutMarker))
+	}
+	steps = append(steps,
+		prepare,
+		"until kubectl get --raw /readyz >/dev/null 2>&1; do sleep 1; done",
+	)
+	if setup != "" {
+		steps = append(steps, setup)
+	}
+	// Service account tokens are only minted by the controller manager
+	if serviceAccount {
+		steps = append(steps, serviceAccountKubeconfig(c.Image()))
+	}
+	steps = append(steps, fmt.Sprintf("touch %s/%s", progressDir, bootstrappedMarker))
+	for key := range c.Spec.ClusterYAML {
+		steps = append(steps, fmt.Sprintf("kubectl apply -f /honk/%d.yaml", key))
+	}
+	steps = append(steps, fmt.Sprintf("kubectl create ns honk && touch %s/%s && sleep infinity", progressDir, manifestsAppliedMarker))
+
+	return v1.Container{
+		Name:    "bootstrap",
+		Image:   kubectlImage,
+		Command: []string{"bash", "-c", strings.Join(steps, " && ")},
+		Env: []v1.EnvVar{
+			{Name: "KUBECONFIG", Value: controlPlaneKubeconfigDir + "/config"},
+		},
+		ReadinessProbe: &v1.Probe{
+			PeriodSeconds:  5,
+			TimeoutSeconds: 30,
+			Handler: v1.Handler{
+				Exec: &v1.ExecAction{
+					Command: []string{"bash", "-c", readinessCheck},
+				},
+			},
+		},
+		VolumeMounts: append(mounts, v1.VolumeMount{
+			Name:      "honk",
+			MountPath: "/honk",
+		}),
+	}
+}
+
+// controlPlanePodSpec runs etcd and kube-apiserver, optionally kube-controller-manager, and any
+// extra containers as plain unprivileged containers next to the bootstrap container. They share
+// the credentials from the bootstrap Secret.
+func (c Cluster) controlPlanePodSpec(setup, readinessCheck string, controllerManager bool, extra ...v1.Container) v1.PodSpec {
+	falseValue := false
+	defaultMode := int32(0644)
+	version := c.Image()
+
+	pkiMount := v1.VolumeMount{
+		Name:      "pki",
+		MountPath: pkiDir,
+		ReadOnly:  true,
+	}
+	prepare := fmt.Sprintf("cp %s/admin.conf %s/config", pkiDir, controlPlaneKubeconfigDir)
+
+	apiServer := []string{
+		"kube-apiserver",
+		"--bind-address=0.0.0.0",
+		"--secure-port=6443",
+	}
+	// The insecure port is off by default from 1.20 and the flag is gone in 1.24
+	if minor, ok := kubernetesMinor(version); ok && minor < 24 {
+		apiServer = append(apiServer, "--insecure-port=0")
+	}
+	apiServer = append(apiServer,
+		"--etcd-servers=http://127.0.0.1:2379",
+		"--client-ca-file="+pkiDir+"/ca.crt",
+		"--tls-cert-file="+pkiDir+"/apiserver.crt",
+		"--tls-private-key-file="+pkiDir+"/apiserver.key",
+		"--service-account-key-file="+pkiDir+"/sa.pub",
+		"--service-account-signing-key-file="+pkiDir+"/sa.key",
+		"--service-account-issuer=https://kubernetes.default.svc.cluster.local",
+		"--service-cluster-ip-range=10.96.0.0/12",
+		"--authorization-mode=Node,RBAC",
+		"--allow-privileged=true",
+	)
+
+	containers := []v1.Container{
+		c.bootstrapContainer(prepare, setup, readinessCheck, controllerManager, pkiMount),
+		{
+			Name:  "etcd",
+			Image: etcdImage(version),
+			Command: []string{
+				"etcd",
+				"--data-dir=/var/lib/etcd",
+				"--listen-client-urls=http://127.0.0.1:2379",
+				"--advertise-client-urls=http://127.0.0.1:2379",
+			},
+			VolumeMounts: []v1.VolumeMount{
+				{
+					Name:      "etcd-data",
+					MountPath: "/var/lib/etcd",
+				},
+			},
+			Resources: componentRequests("100m", "100Mi"),
+		},
+		{
+			Name:         "kube-apiserver",
+			Image:        fmt.Sprintf("k8s.gcr.io/kube-apiserver:%s", version),
+			Command:      apiServer,
+			VolumeMounts: []v1.VolumeMount{pkiMount},
+			Resources:    c.resources(),
+		},
+	}
+	if controllerManager {
+		containers = append(containers, v1.Container{
+			Name:  "kube-controller-manager",
+			Image: fmt.Sprintf("k8s.gcr.io/kube-controller-manager:%s", version),
+			Command: []string{
+				"kube-controller-manager",
+				"--kubeconfig=" + pkiDir + "/controller-manager.conf",
+				"--authentication-kubeconfig=" + pkiDir + "/controller-manager.conf",
+				"--authorization-kubeconfig=" + pkiDir + "/controller-manager.conf",
+				"--service-account-private-key-file=" + pkiDir + "/sa.key",
+				"--root-ca-file=" + pkiDir + "/ca.crt",
+				"--cluster-signing-cert-file=" + pkiDir + "/ca.crt",
+				"--cluster-signing-key-file=" + pkiDir + "/ca.key",
+				"--use-service-account-credentials=true",
+				"--leader-elect=false",
+			},
+			VolumeMounts: []v1.VolumeMount{pkiMount},
+			Resources:    componentRequests("200m", "100Mi"),
+		})
+	}
+	for _, container := range extra {
+		container.VolumeMounts = append(container.VolumeMounts, pkiMount)
+		containers = append(containers, container)
+	}
+
+	return v1.PodSpec{
+		AutomountServiceAccountToken: &falseValue,
+		EnableServiceLinks:           &falseValue,
+		// A failed bootstrap should fail the pod rather than crash-loop, the controller decides whether to retry
+		RestartPolicy: v1.RestartPolicyNever,
+		Containers:    containers,
+		Volumes: []v1.Volume{
+			{
+				Name: "pki",
+				VolumeSource: v1.VolumeSource{
+					Secret: &v1.SecretVolumeSource{
+						SecretName:  c.BootstrapSecretName(),
+						DefaultMode: &defaultMode,
+					},
+				},
+			},
+			{
+				Name: "etcd-data",
+				VolumeSource: v1.VolumeSource{
+					EmptyDir: &v1.EmptyDirVolumeSource{},
+				},
+			},
+			{
+				Name: "honk",
+				VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: c.Name,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,77 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestControlPlanePodSpec(t *testing.T) {
+	tests := []struct {
+		image            string
+		wantEtcd         string
+		wantInsecurePort bool
+		wantTokenSecret  bool
+	}{
+		{image: "v1.15.3", wantEtcd: "k8s.gcr.io/etcd:3.3.15-0", wantInsecurePort: true},
+		{image: "v1.18.0", wantEtcd: "k8s.gcr.io/etcd:3.4.3-0", wantInsecurePort: true},
+		{image: "1.20.2", wantEtcd: "k8s.gcr.io/etcd:3.4.13-0", wantInsecurePort: true},
+		{image: "v1.23.17", wantEtcd: "k8s.gcr.io/etcd:3.5.1-0", wantInsecurePort: true},
+		{image: "v1.24.0-rc.1", wantEtcd: "k8s.gcr.io/etcd:3.5.3-0", wantInsecurePort: false, wantTokenSecret: true},
+		{image: "v1.33.1", wantEtcd: "k8s.gcr.io/etcd:3.5.12-0", wantInsecurePort: false, wantTokenSecret: true},
+		{image: "latest", wantEtcd: "k8s.gcr.io/etcd:3.5.12-0", wantInsecurePort: false, wantTokenSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = ControlPlaneCluster
+			c.Spec.Image = tt.image
+
+			containers := map[string]v1.Container{}
+			for _, container := range c.controlPlanePodSpec("", DefaultReadinessCheck, true).Containers {
+				containers[container.Name] = container
+			}
+
+			if got := containers["etcd"].Image; got != tt.wantEtcd {
+				t.Errorf("etcd image = %q, want %q", got, tt.wantEtcd)
+			}
+			insecurePort := false
+			for _, arg := range containers["kube-apiserver"].Command {
+				insecurePort = insecurePort || arg == "--insecure-port=0"
+			}
+			if insecurePort != tt.wantInsecurePort {
+				t.Errorf("--insecure-port=0: %v, want %v", insecurePort, tt.wantInsecurePort)
+			}
+			bootstrap := strings.Join(containers["bootstrap"].Command, " ")
+			if got := strings.Contains(bootstrap, "kubernetes.io/service-account-token"); got != tt.wantTokenSecret {
+				t.Errorf("creates a token Secret: %v, want %v", got, tt.wantTokenSecret)
+			}
+			if got := strings.Contains(bootstrap, "{.secrets[0].name}"); got == tt.wantTokenSecret {
+				t.Errorf("reads the service account's own Secret: %v, want %v", got, !tt.wantTokenSecret)
+			}
+			for _, name := range []string{"etcd", "kube-controller-manager"} {
+				requests := containers[name].Resources.Requests
+				if requests.Cpu().IsZero() || requests.Memory().IsZero() {
+					t.Errorf("%s requests = %v, want cpu and memory", name, requests)
+				}
+			}
+		})
+	}
+}
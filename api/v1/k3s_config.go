@@ -0,0 +1,234 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// k3dClusterName is the name of the k3d cluster inside every k3s Cluster pod
+const k3dClusterName = "kaas"
+
+// k3sComponents are the values K3sConfig.Disable accepts
+var k3sComponents = []string{"coredns", "servicelb", "traefik", "local-storage", "metrics-server"}
+
+// k3sManagedFlags are set by kaas and can't be overridden through serverArgs or agentArgs
+var k3sManagedFlags = []string{"--https-listen-port", "--write-kubeconfig", "--write-kubeconfig-mode", "--token", "--token-file", "--data-dir", "--server"}
+
+// k3dConfig is the subset of k3d's v1alpha4 Simple config kaas renders
+type k3dConfig struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Metadata   k3dMetadata      `json:"metadata"`
+	Servers    int32            `json:"servers"`
+	Agents     int32            `json:"agents"`
+	KubeAPI    k3dKubeAPI       `json:"kubeAPI"`
+	Image      string           `json:"image"`
+	Volumes    []k3dVolume      `json:"volumes,omitempty"`
+	Registries *k3dRegistries   `json:"registries,omitempty"`
+	Options    k3dConfigOptions `json:"options"`
+}
+
+type k3dMetadata struct {
+	Name string `json:"name"`
+}
+
+type k3dKubeAPI struct {
+	Host     string `json:"host"`
+	HostIP   string `json:"hostIP"`
+	HostPort string `json:"hostPort"`
+}
+
+type k3dVolume struct {
+	Volume      string   `json:"volume"`
+	NodeFilters []string `json:"nodeFilters"`
+}
+
+type k3dRegistries struct {
+	Config string `json:"config"`
+}
+
+type k3dConfigOptions struct {
+	K3s        k3dK3sOptions        `json:"k3s"`
+	Kubeconfig k3dKubeconfigOptions `json:"kubeconfig"`
+}
+
+type k3dK3sOptions struct {
+	ExtraArgs []k3dArg `json:"extraArgs,omitempty"`
+}
+
+type k3dArg struct {
+	Arg         string   `json:"arg"`
+	NodeFilters []string `json:"nodeFilters"`
+}
+
+type k3dKubeconfigOptions struct {
+	UpdateDefaultKubeconfig bool `json:"updateDefaultKubeconfig"`
+	SwitchCurrentContext    bool `json:"switchCurrentContext"`
+}
+
+// K3dConfig renders the Cluster's k3s configuration into a k3d config file, with the
+// API server published on 0.0.0.0:6443 like every other cluster type
+func (c Cluster) K3dConfig() (string, error) {
+	k3s := K3sConfig{}
+	if c.Spec.K3s != nil {
+		k3s = *c.Spec.K3s
+	}
+
+	config := k3dConfig{
+		APIVersion: "k3d.io/v1alpha4",
+		Kind:       "Simple",
+		Metadata:   k3dMetadata{Name: k3dClusterName},
+		Servers:    1,
+		KubeAPI: k3dKubeAPI{
+			Host:     "0.0.0.0",
+			HostIP:   "0.0.0.0",
+			HostPort: "6443",
+		},
+		Image: c.Image(),
+	}
+	if c.Spec.Workers != nil {
+		config.Agents = *c.Spec.Workers
+	}
+
+	for _, arg := range k3s.serverArgs() {
+		config.Options.K3s.ExtraArgs = append(config.Options.K3s.ExtraArgs, k3dArg{Arg: arg, NodeFilters: []string{"server:*"}})
+	}
+	for _, arg := range k3s.AgentArgs {
+		config.Options.K3s.ExtraArgs = append(config.Options.K3s.ExtraArgs, k3dArg{Arg: arg, NodeFilters: []string{"agent:*"}})
+	}
+
+	for _, volume := range k3s.Volumes {
+		nodeFilters := volume.NodeFilters
+		if len(nodeFilters) == 0 {
+			nodeFilters = []string{"all"}
+		}
+		config.Volumes = append(config.Volumes, k3dVolume{
+			Volume:      fmt.Sprintf("%s:%s", volume.HostPath, volume.MountPath),
+			NodeFilters: nodeFilters,
+		})
+	}
+
+	if k3s.Registries != nil {
+		registries, err := k3s.RegistriesYAML()
+		if err != nil {
+			return "", err
+		}
+		config.Registries = &k3dRegistries{Config: registries}
+	}
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling k3d config: %s", err.Error())
+	}
+	return string(data), nil
+}
+
+// RegistriesYAML renders the registries as a k3s registries.yaml
+func (k K3sConfig) RegistriesYAML() (string, error) {
+	data, err := yaml.Marshal(k.Registries)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling registries: %s", err.Error())
+	}
+	return string(data), nil
+}
+
+// serverArgs are the extra flags for the k3s server, including the disabled components
+func (k K3sConfig) serverArgs() []string {
+	var args []string
+	for _, component := range k.Disable {
+		args = append(args, fmt.Sprintf("--disable=%s", component))
+	}
+	return append(args, k.ServerArgs...)
+}
+
+// validate checks the k3s configuration, allowing volumes only for k3d based clusters
+func (k K3sConfig) validate(fldPath *field.Path, allowVolumes bool) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validateK3sArgs(fldPath.Child("serverArgs"), k.ServerArgs)...)
+	allErrs = append(allErrs, validateK3sArgs(fldPath.Child("agentArgs"), k.AgentArgs)...)
+
+	for i, component := range k.Disable {
+		if !containsString(k3sComponents, string(component)) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("disable").Index(i), component, k3sComponents))
+		}
+	}
+
+	if k.Registries != nil {
+		for host, mirror := range k.Registries.Mirrors {
+			mirrorPath := fldPath.Child("registries", "mirrors").Key(host)
+			if len(mirror.Endpoints) == 0 {
+				allErrs = append(allErrs, field.Required(mirrorPath.Child("endpoint"), "at least one endpoint is required"))
+			}
+			for i, endpoint := range mirror.Endpoints {
+				if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					allErrs = append(allErrs, field.Invalid(mirrorPath.Child("endpoint").Index(i), endpoint, "must be an http or https URL"))
+				}
+			}
+		}
+	}
+
+	if len(k.Volumes) > 0 && !allowVolumes {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("volumes"), "volumes are only supported for k3s clusters"))
+	}
+	for i, volume := range k.Volumes {
+		volumePath := fldPath.Child("volumes").Index(i)
+		if !validK3sVolumePath(volume.HostPath) {
+			allErrs = append(allErrs, field.Invalid(volumePath.Child("hostPath"), volume.HostPath, "must be a clean absolute path without colons"))
+		}
+		if !validK3sVolumePath(volume.MountPath) {
+			allErrs = append(allErrs, field.Invalid(volumePath.Child("mountPath"), volume.MountPath, "must be a clean absolute path without colons"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateK3sArgs checks that args are flags kaas doesn't set itself
+func validateK3sArgs(fldPath *field.Path, args []string) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, arg := range args {
+		flag := strings.SplitN(arg, "=", 2)[0]
+		if !strings.HasPrefix(flag, "--") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), arg, "must be a flag such as --kube-apiserver-arg=v=4"))
+		} else if containsString(k3sManagedFlags, flag) {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Index(i), fmt.Sprintf("%s is managed by kaas", flag)))
+		}
+	}
+	return allErrs
+}
+
+// validK3sVolumePath returns whether dir can be used in a k3d src:dest volume
+func validK3sVolumePath(dir string) bool {
+	return path.IsAbs(dir) && path.Clean(dir) == dir && !strings.Contains(dir, ":")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestK3dConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		workers        *int32
+		k3s            *K3sConfig
+		wantAgents     int32
+		wantExtraArgs  []k3dArg
+		wantVolumes    []k3dVolume
+		wantRegistries string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:       "workers",
+			workers:    int32Ptr(2),
+			wantAgents: 2,
+		},
+		{
+			name: "server and agent args",
+			k3s: &K3sConfig{
+				ServerArgs: []string{"--kube-apiserver-arg=v=4"},
+				AgentArgs:  []string{"--node-label=pool=agents"},
+				Disable:    []K3sComponent{"traefik"},
+			},
+			wantExtraArgs: []k3dArg{
+				{Arg: "--disable=traefik", NodeFilters: []string{"server:*"}},
+				{Arg: "--kube-apiserver-arg=v=4", NodeFilters: []string{"server:*"}},
+				{Arg: "--node-label=pool=agents", NodeFilters: []string{"agent:*"}},
+			},
+		},
+		{
+			name: "volumes",
+			k3s: &K3sConfig{
+				Volumes: []K3sVolume{
+					{HostPath: "/data", MountPath: "/mnt/data"},
+					{HostPath: "/cache", MountPath: "/mnt/cache", NodeFilters: []string{"server:0"}},
+				},
+			},
+			wantVolumes: []k3dVolume{
+				{Volume: "/data:/mnt/data", NodeFilters: []string{"all"}},
+				{Volume: "/cache:/mnt/cache", NodeFilters: []string{"server:0"}},
+			},
+		},
+		{
+			name: "registries",
+			k3s: &K3sConfig{
+				Registries: &K3sRegistries{
+					Mirrors: map[string]K3sRegistryMirror{
+						"docker.io": {Endpoints: []string{"https://mirror.example.com"}},
+					},
+				},
+			},
+			wantRegistries: "https://mirror.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = K3sCluster
+			c.Spec.Workers = tt.workers
+			c.Spec.K3s = tt.k3s
+
+			data, err := c.K3dConfig()
+			if err != nil {
+				t.Fatalf("K3dConfig() error = %v", err)
+			}
+			var got k3dConfig
+			if err := yaml.Unmarshal([]byte(data), &got); err != nil {
+				t.Fatalf("K3dConfig() = %q, not valid YAML: %v", data, err)
+			}
+
+			if got.APIVersion != "k3d.io/v1alpha4" || got.Kind != "Simple" {
+				t.Errorf("K3dConfig() is a %s %s, want a k3d.io/v1alpha4 Simple", got.APIVersion, got.Kind)
+			}
+			if got.KubeAPI != (k3dKubeAPI{Host: "0.0.0.0", HostIP: "0.0.0.0", HostPort: "6443"}) {
+				t.Errorf("K3dConfig() kubeAPI = %+v, want 0.0.0.0:6443", got.KubeAPI)
+			}
+			if got.Image != DefaultK3sImage {
+				t.Errorf("K3dConfig() image = %q, want %q", got.Image, DefaultK3sImage)
+			}
+			if got.Servers != 1 || got.Agents != tt.wantAgents {
+				t.Errorf("K3dConfig() servers, agents = %d, %d, want 1, %d", got.Servers, got.Agents, tt.wantAgents)
+			}
+			if !reflect.DeepEqual(got.Options.K3s.ExtraArgs, tt.wantExtraArgs) {
+				t.Errorf("K3dConfig() extraArgs = %+v, want %+v", got.Options.K3s.ExtraArgs, tt.wantExtraArgs)
+			}
+			if !reflect.DeepEqual(got.Volumes, tt.wantVolumes) {
+				t.Errorf("K3dConfig() volumes = %+v, want %+v", got.Volumes, tt.wantVolumes)
+			}
+			switch {
+			case tt.wantRegistries == "" && got.Registries != nil:
+				t.Errorf("K3dConfig() registries = %+v, want none", got.Registries)
+			case tt.wantRegistries != "" && (got.Registries == nil || !strings.Contains(got.Registries.Config, tt.wantRegistries)):
+				t.Errorf("K3dConfig() registries = %+v, want a config with %s", got.Registries, tt.wantRegistries)
+			}
+		})
+	}
+}
+
+func TestK3sConfigValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		k3s          K3sConfig
+		allowVolumes bool
+		want         []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			k3s: K3sConfig{
+				ServerArgs: []string{"--kube-apiserver-arg=v=4"},
+				AgentArgs:  []string{"--node-label=pool=agents"},
+				Disable:    []K3sComponent{"traefik", "metrics-server"},
+				Registries: &K3sRegistries{
+					Mirrors: map[string]K3sRegistryMirror{
+						"docker.io": {Endpoints: []string{"https://mirror.example.com", "http://10.0.0.1:5000"}},
+					},
+				},
+				Volumes: []K3sVolume{{HostPath: "/data", MountPath: "/mnt/data"}},
+			},
+			allowVolumes: true,
+		},
+		{
+			name: "not a flag",
+			k3s:  K3sConfig{ServerArgs: []string{"v=4"}, AgentArgs: []string{"-v=4"}},
+			want: []string{"spec.k3s.serverArgs[0]", "spec.k3s.agentArgs[0]"},
+		},
+		{
+			name: "managed flags",
+			k3s:  K3sConfig{ServerArgs: []string{"--https-listen-port=7443"}, AgentArgs: []string{"--token", "--server=https://x"}},
+			want: []string{"spec.k3s.serverArgs[0]", "spec.k3s.agentArgs[0]", "spec.k3s.agentArgs[1]"},
+		},
+		{
+			name: "unknown component",
+			k3s:  K3sConfig{Disable: []K3sComponent{"coredns", "flannel"}},
+			want: []string{"spec.k3s.disable[1]"},
+		},
+		{
+			name: "mirror without endpoints",
+			k3s: K3sConfig{Registries: &K3sRegistries{
+				Mirrors: map[string]K3sRegistryMirror{"docker.io": {}},
+			}},
+			want: []string{"spec.k3s.registries.mirrors[docker.io].endpoint"},
+		},
+		{
+			name: "invalid endpoints",
+			k3s: K3sConfig{Registries: &K3sRegistries{
+				Mirrors: map[string]K3sRegistryMirror{"docker.io": {Endpoints: []string{"mirror.example.com", "ftp://mirror.example.com", "https://"}}},
+			}},
+			want: []string{
+				"spec.k3s.registries.mirrors[docker.io].endpoint[0]",
+				"spec.k3s.registries.mirrors[docker.io].endpoint[1]",
+				"spec.k3s.registries.mirrors[docker.io].endpoint[2]",
+			},
+		},
+		{
+			name: "volumes without k3d",
+			k3s:  K3sConfig{Volumes: []K3sVolume{{HostPath: "/data", MountPath: "/mnt/data"}}},
+			want: []string{"spec.k3s.volumes"},
+		},
+		{
+			name: "invalid volume paths",
+			k3s: K3sConfig{Volumes: []K3sVolume{
+				{HostPath: "data", MountPath: "/mnt/data/"},
+				{HostPath: "/data:ro", MountPath: "/mnt/../data"},
+			}},
+			allowVolumes: true,
+			want: []string{
+				"spec.k3s.volumes[0].hostPath",
+				"spec.k3s.volumes[0].mountPath",
+				"spec.k3s.volumes[1].hostPath",
+				"spec.k3s.volumes[1].mountPath",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range tt.k3s.validate(field.NewPath("spec", "k3s"), tt.allowVolumes) {
+				got = append(got, err.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
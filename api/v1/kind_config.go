@@ -0,0 +1,204 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net"
+	"path"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+const (
+	// kindAPIServerPort and kindAPIServerAddress are where every kind cluster's API server listens inside the pod
+	kindAPIServerPort    = 6443
+	kindAPIServerAddress = "0.0.0.0"
+)
+
+// kindConfigFile adds the featureGates newer kind releases understand to the v1alpha4 Cluster
+type kindConfigFile struct {
+	v1alpha4.Cluster `yaml:",inline"`
+	FeatureGates     map[string]bool `yaml:"featureGates,omitempty"`
+}
+
+// KindConfig generates a valid KindConfig, makes updates, then returns a string
+func (c Cluster) KindConfig() (string, error) {
+	kindConfig, err := c.parseKindConfig()
+	if err != nil {
+		return "", err
+	}
+
+	kindConfig.Networking.APIServerPort = kindAPIServerPort
+	kindConfig.Networking.APIServerAddress = kindAPIServerAddress
+
+	var data []byte
+	if c.Spec.Kind != nil {
+		data, err = yaml.Marshal(kindConfigFile{Cluster: *kindConfig, FeatureGates: c.Spec.Kind.FeatureGates})
+	} else {
+		data, err = yaml.Marshal(kindConfig)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error marshalling kindconfig: %s", err.Error())
+	}
+
+	return string(data), nil
+}
+
+// parseKindConfig converts spec.kind, or parses spec.clusterSpec when it isn't set
+func (c Cluster) parseKindConfig() (*v1alpha4.Cluster, error) {
+	if c.Spec.Kind != nil {
+		return c.Spec.Kind.toV1alpha4(), nil
+	}
+
+	kindConfig := &v1alpha4.Cluster{}
+	err := yaml.Unmarshal([]byte(c.Spec.ClusterSpec), kindConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling kindconfig: %s", err.Error())
+	}
+	return kindConfig, nil
+}
+
+// kindOverrides lists the fields of a parsed kind config that KindConfig and the
+// kind create command replace
+func (c Cluster) kindOverrides(kindConfig *v1alpha4.Cluster) []string {
+	var overridden []string
+	if port := kindConfig.Networking.APIServerPort; port != 0 && port != kindAPIServerPort {
+		overridden = append(overridden, "networking.apiServerPort")
+	}
+	if address := kindConfig.Networking.APIServerAddress; address != "" && address != kindAPIServerAddress {
+		overridden = append(overridden, "networking.apiServerAddress")
+	}
+	// kind create cluster --image applies spec.image to every node
+	for i, node := range kindConfig.Nodes {
+		if node.Image != "" && node.Image != c.Image() {
+			overridden = append(overridden, fmt.Sprintf("nodes[%d].image", i))
+		}
+	}
+	return overridden
+}
+
+// toV1alpha4 converts the typed kind configuration into kind's own config type
+func (k KindSpec) toV1alpha4() *v1alpha4.Cluster {
+	kindConfig := &v1alpha4.Cluster{
+		TypeMeta: v1alpha4.TypeMeta{
+			Kind:       "Cluster",
+			APIVersion: "kind.x-k8s.io/v1alpha4",
+		},
+		KubeadmConfigPatches:    k.KubeadmConfigPatches,
+		ContainerdConfigPatches: k.ContainerdConfigPatches,
+	}
+
+	for _, node := range k.Nodes {
+		kindNode := v1alpha4.Node{
+			Role:                 v1alpha4.NodeRole(node.Role),
+			Image:                node.Image,
+			KubeadmConfigPatches: node.KubeadmConfigPatches,
+		}
+		for _, mount := range node.ExtraMounts {
+			kindNode.ExtraMounts = append(kindNode.ExtraMounts, v1alpha4.Mount{
+				HostPath:       mount.HostPath,
+				ContainerPath:  mount.ContainerPath,
+				Readonly:       mount.ReadOnly,
+				SelinuxRelabel: mount.SelinuxRelabel,
+				Propagation:    v1alpha4.MountPropagation(mount.Propagation),
+			})
+		}
+		kindConfig.Nodes = append(kindConfig.Nodes, kindNode)
+	}
+
+	if k.Networking != nil {
+		kindConfig.Networking = v1alpha4.Networking{
+			IPFamily:          v1alpha4.ClusterIPFamily(k.Networking.IPFamily),
+			PodSubnet:         k.Networking.PodSubnet,
+			ServiceSubnet:     k.Networking.ServiceSubnet,
+			DisableDefaultCNI: k.Networking.DisableDefaultCNI,
+		}
+	}
+
+	return kindConfig
+}
+
+// validate checks the parts of the typed kind configuration kind would only reject inside the pod
+func (k KindSpec) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	controlPlanes := 0
+	for i, node := range k.Nodes {
+		nodePath := fldPath.Child("nodes").Index(i)
+		switch v1alpha4.NodeRole(node.Role) {
+		case v1alpha4.ControlPlaneRole:
+			controlPlanes++
+		case v1alpha4.WorkerRole:
+		default:
+			allErrs = append(allErrs, field.NotSupported(nodePath.Child("role"), node.Role, []string{string(v1alpha4.ControlPlaneRole), string(v1alpha4.WorkerRole)}))
+		}
+
+		for j, mount := range node.ExtraMounts {
+			mountPath := nodePath.Child("extraMounts").Index(j)
+			if !path.IsAbs(mount.HostPath) {
+				allErrs = append(allErrs, field.Invalid(mountPath.Child("hostPath"), mount.HostPath, "must be an absolute path"))
+			}
+			if !path.IsAbs(mount.ContainerPath) {
+				allErrs = append(allErrs, field.Invalid(mountPath.Child("containerPath"), mount.ContainerPath, "must be an absolute path"))
+			}
+			switch v1alpha4.MountPropagation(mount.Propagation) {
+			case "", v1alpha4.MountPropagationNone, v1alpha4.MountPropagationHostToContainer, v1alpha4.MountPropagationBidirectional:
+			default:
+				allErrs = append(allErrs, field.NotSupported(mountPath.Child("propagation"), mount.Propagation, []string{string(v1alpha4.MountPropagationNone), string(v1alpha4.MountPropagationHostToContainer), string(v1alpha4.MountPropagationBidirectional)}))
+			}
+		}
+
+		allErrs = append(allErrs, validateKubeadmConfigPatches(nodePath.Child("kubeadmConfigPatches"), node.KubeadmConfigPatches)...)
+	}
+	if len(k.Nodes) > 0 && controlPlanes == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("nodes"), "at least one node must be a control-plane"))
+	}
+
+	if k.Networking != nil {
+		networkingPath := fldPath.Child("networking")
+		switch v1alpha4.ClusterIPFamily(k.Networking.IPFamily) {
+		case "", v1alpha4.IPv4Family, v1alpha4.IPv6Family:
+		default:
+			allErrs = append(allErrs, field.NotSupported(networkingPath.Child("ipFamily"), k.Networking.IPFamily, []string{string(v1alpha4.IPv4Family), string(v1alpha4.IPv6Family)}))
+		}
+		if _, _, err := net.ParseCIDR(k.Networking.PodSubnet); k.Networking.PodSubnet != "" && err != nil {
+			allErrs = append(allErrs, field.Invalid(networkingPath.Child("podSubnet"), k.Networking.PodSubnet, "must be a CIDR such as 10.244.0.0/16"))
+		}
+		if _, _, err := net.ParseCIDR(k.Networking.ServiceSubnet); k.Networking.ServiceSubnet != "" && err != nil {
+			allErrs = append(allErrs, field.Invalid(networkingPath.Child("serviceSubnet"), k.Networking.ServiceSubnet, "must be a CIDR such as 10.96.0.0/12"))
+		}
+	}
+
+	allErrs = append(allErrs, validateKubeadmConfigPatches(fldPath.Child("kubeadmConfigPatches"), k.KubeadmConfigPatches)...)
+
+	return allErrs
+}
+
+// validateKubeadmConfigPatches checks that every patch is a YAML object
+func validateKubeadmConfigPatches(fldPath *field.Path, patches []string) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, patch := range patches {
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(patch), &object); err != nil || len(object) == 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), patch, "must be a YAML object"))
+		}
+	}
+	return allErrs
+}
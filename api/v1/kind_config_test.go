@@ -0,0 +1,238 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+func TestKindConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		clusterSpec      string
+		kind             *KindSpec
+		wantErr          bool
+		wantNodes        []v1alpha4.NodeRole
+		wantPodSubnet    string
+		wantFeatureGates map[string]bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:          "clusterSpec",
+			clusterSpec:   "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\nnodes:\n- role: control-plane\n- role: worker\nnetworking:\n  apiServerPort: 1234\n  apiServerAddress: 127.0.0.1\n  podSubnet: 10.10.0.0/16\n",
+			wantNodes:     []v1alpha4.NodeRole{v1alpha4.ControlPlaneRole, v1alpha4.WorkerRole},
+			wantPodSubnet: "10.10.0.0/16",
+		},
+		{
+			name:        "invalid clusterSpec",
+			clusterSpec: "nodes: [",
+			wantErr:     true,
+		},
+		{
+			name: "spec.kind",
+			kind: &KindSpec{
+				Nodes:        []KindNode{{Role: "control-plane"}, {Role: "worker"}, {Role: "worker"}},
+				Networking:   &KindNetworking{PodSubnet: "10.20.0.0/16"},
+				FeatureGates: map[string]bool{"EphemeralContainers": true},
+			},
+			wantNodes:        []v1alpha4.NodeRole{v1alpha4.ControlPlaneRole, v1alpha4.WorkerRole, v1alpha4.WorkerRole},
+			wantPodSubnet:    "10.20.0.0/16",
+			wantFeatureGates: map[string]bool{"EphemeralContainers": true},
+		},
+		{
+			name:        "spec.kind wins over clusterSpec",
+			clusterSpec: "nodes: [",
+			kind:        &KindSpec{Nodes: []KindNode{{Role: "control-plane"}}},
+			wantNodes:   []v1alpha4.NodeRole{v1alpha4.ControlPlaneRole},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = KindCluster
+			c.Spec.ClusterSpec = tt.clusterSpec
+			c.Spec.Kind = tt.kind
+
+			data, err := c.KindConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KindConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got kindConfigFile
+			if err := yaml.Unmarshal([]byte(data), &got); err != nil {
+				t.Fatalf("KindConfig() = %q, not valid YAML: %v", data, err)
+			}
+
+			if got.Networking.APIServerPort != kindAPIServerPort || got.Networking.APIServerAddress != kindAPIServerAddress {
+				t.Errorf("KindConfig() API server = %s:%d, want %s:%d", got.Networking.APIServerAddress, got.Networking.APIServerPort, kindAPIServerAddress, kindAPIServerPort)
+			}
+			var nodes []v1alpha4.NodeRole
+			for _, node := range got.Nodes {
+				nodes = append(nodes, node.Role)
+			}
+			if !reflect.DeepEqual(nodes, tt.wantNodes) {
+				t.Errorf("KindConfig() nodes = %v, want %v", nodes, tt.wantNodes)
+			}
+			if got.Networking.PodSubnet != tt.wantPodSubnet {
+				t.Errorf("KindConfig() podSubnet = %q, want %q", got.Networking.PodSubnet, tt.wantPodSubnet)
+			}
+			if !reflect.DeepEqual(got.FeatureGates, tt.wantFeatureGates) {
+				t.Errorf("KindConfig() featureGates = %v, want %v", got.FeatureGates, tt.wantFeatureGates)
+			}
+		})
+	}
+}
+
+func TestKindOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		image       string
+		clusterSpec string
+		want        []string
+	}{
+		{
+			name: "nothing set",
+		},
+		{
+			name:        "kaas' own values",
+			clusterSpec: "networking:\n  apiServerPort: 6443\n  apiServerAddress: 0.0.0.0\nnodes:\n- role: control-plane\n  image: " + DefaultKindImage + "\n",
+		},
+		{
+			name:        "API server",
+			clusterSpec: "networking:\n  apiServerPort: 1234\n  apiServerAddress: 127.0.0.1\n",
+			want:        []string{"networking.apiServerPort", "networking.apiServerAddress"},
+		},
+		{
+			name:        "node images",
+			clusterSpec: "nodes:\n- role: control-plane\n- role: worker\n  image: kindest/node:v1.17.0\n",
+			want:        []string{"nodes[1].image"},
+		},
+		{
+			name:        "node images matching spec.image",
+			image:       "kindest/node:v1.17.0",
+			clusterSpec: "nodes:\n- role: control-plane\n  image: kindest/node:v1.17.0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = KindCluster
+			c.Spec.Image = tt.image
+			c.Spec.ClusterSpec = tt.clusterSpec
+
+			kindConfig, err := c.parseKindConfig()
+			if err != nil {
+				t.Fatalf("parseKindConfig() error = %v", err)
+			}
+			if got := c.kindOverrides(kindConfig); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kindOverrides() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindSpecValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		kind KindSpec
+		want []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			kind: KindSpec{
+				Nodes: []KindNode{
+					{Role: "control-plane", KubeadmConfigPatches: []string{"kind: InitConfiguration\n"}},
+					{Role: "worker", ExtraMounts: []KindMount{{HostPath: "/data", ContainerPath: "/data", Propagation: "HostToContainer"}}},
+				},
+				Networking:           &KindNetworking{IPFamily: "ipv4", PodSubnet: "10.244.0.0/16", ServiceSubnet: "10.96.0.0/12"},
+				KubeadmConfigPatches: []string{"kind: ClusterConfiguration\n"},
+			},
+		},
+		{
+			name: "unknown role",
+			kind: KindSpec{Nodes: []KindNode{{Role: "control-plane"}, {Role: "etcd"}}},
+			want: []string{"spec.kind.nodes[1].role"},
+		},
+		{
+			name: "no control plane",
+			kind: KindSpec{Nodes: []KindNode{{Role: "worker"}}},
+			want: []string{"spec.kind.nodes"},
+		},
+		{
+			name: "invalid mounts",
+			kind: KindSpec{Nodes: []KindNode{{
+				Role: "control-plane",
+				ExtraMounts: []KindMount{
+					{HostPath: "data", ContainerPath: "/data"},
+					{HostPath: "/data", ContainerPath: "data", Propagation: "Shared"},
+				},
+			}}},
+			want: []string{
+				"spec.kind.nodes[0].extraMounts[0].hostPath",
+				"spec.kind.nodes[0].extraMounts[1].containerPath",
+				"spec.kind.nodes[0].extraMounts[1].propagation",
+			},
+		},
+		{
+			name: "invalid networking",
+			kind: KindSpec{Networking: &KindNetworking{IPFamily: "dual", PodSubnet: "10.244.0.0", ServiceSubnet: "services"}},
+			want: []string{
+				"spec.kind.networking.ipFamily",
+				"spec.kind.networking.podSubnet",
+				"spec.kind.networking.serviceSubnet",
+			},
+		},
+		{
+			name: "invalid kubeadm patches",
+			kind: KindSpec{
+				Nodes:                []KindNode{{Role: "control-plane", KubeadmConfigPatches: []string{"- a list"}}},
+				KubeadmConfigPatches: []string{"", "kind: [", "kind: ClusterConfiguration\n"},
+			},
+			want: []string{
+				"spec.kind.nodes[0].kubeadmConfigPatches[0]",
+				"spec.kind.kubeadmConfigPatches[0]",
+				"spec.kind.kubeadmConfigPatches[1]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range tt.kind.validate(field.NewPath("spec", "kind")) {
+				got = append(got, err.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// pkiValidity is how long the certificates of a control plane Cluster are valid for
+const pkiValidity = 10 * 365 * 24 * time.Hour
+
+// controlPlanePKI generates the certificates, service account keys and kubeconfigs
+// a bare etcd + kube-apiserver control plane needs, keyed by file name
+func controlPlanePKI(c Cluster) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "kubernetes"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caCert, caPEM, err := signCertificate(caTemplate, caKey, nil, caKey)
+	if err != nil {
+		return nil, err
+	}
+	files["ca.crt"] = caPEM
+	if files["ca.key"], err = encodeKey(caKey); err != nil {
+		return nil, err
+	}
+
+	if err := addKeyPair(files, "apiserver", c.servingCertificateTemplate(), caCert, caKey); err != nil {
+		return nil, err
+	}
+
+	saKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if files["sa.key"], err = encodeKey(saKey); err != nil {
+		return nil, err
+	}
+	saPub, err := x509.MarshalPKIXPublicKey(&saKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	files["sa.pub"] = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: saPub})
+
+	// admin.conf points at 0.0.0.0:6443 like every other cluster type, so the controller can rewrite it
+	clients := []struct {
+		file, commonName, organization, server string
+	}{
+		{"admin.conf", "kubernetes-admin", "system:masters", "https://0.0.0.0:6443"},
+		{"controller-manager.conf", "system:kube-controller-manager", "", "https://127.0.0.1:6443"},
+	}
+	for _, client := range clients {
+		template := &x509.Certificate{
+			Subject:     pkix.Name{CommonName: client.commonName},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		if client.organization != "" {
+			template.Subject.Organization = []string{client.organization}
+		}
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		_, certPEM, err := signCertificate(template, key, caCert, caKey)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := encodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if files[client.file], err = kubeconfig(client.server, client.commonName, caPEM, certPEM, keyPEM); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// servingCertificateTemplate describes the API server's certificate, which has to be valid
+// inside the pod, through the Cluster's Service and on any extra hosts such as its ClusterIP
+// and the external endpoint
+func (c Cluster) servingCertificateTemplate(hosts ...string) *x509.Certificate {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kube-apiserver"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames: []string{
+			"localhost",
+			"kubernetes",
+			"kubernetes.default",
+			"kubernetes.default.svc",
+			"kubernetes.default.svc.cluster.local",
+			c.Name,
+			fmt.Sprintf("%s.%s", c.Name, c.Namespace),
+			fmt.Sprintf("%s.%s.svc", c.Name, c.Namespace),
+		},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("0.0.0.0"), net.ParseIP("10.96.0.1")},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	return template
+}
+
+// EnsureServingCertificate makes sure the API server certificate in the bootstrap Secret data
+// is valid for every host, which can only be known once the Service and the pod exist. If it
+// isn't, a new certificate signed by the same CA replaces it in data and true is returned.
+// Data without an API server certificate, as for provisioners that don't use one, is left alone.
+func (c Cluster) EnsureServingCertificate(data map[string][]byte, hosts ...string) (bool, error) {
+	if len(data["apiserver.crt"]) == 0 {
+		return false, nil
+	}
+
+	current, err := parseCertificate(data["apiserver.crt"])
+	if err != nil {
+		return false, err
+	}
+	var missing []string
+	for _, host := range hosts {
+		if host != "" && current.VerifyHostname(host) != nil {
+			missing = append(missing, host)
+		}
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	caCert, err := parseCertificate(data["ca.crt"])
+	if err != nil {
+		return false, err
+	}
+	block, _ := pem.Decode(data["ca.key"])
+	if block == nil {
+		return false, fmt.Errorf("ca.key isn't PEM encoded")
+	}
+	caKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return false, err
+	}
+
+	// Keep the hosts the current certificate was already re-issued for
+	defaults := c.servingCertificateTemplate()
+	seen := make(map[string]bool)
+	var extra []string
+	for _, ip := range current.IPAddresses {
+		missing = append(missing, ip.String())
+	}
+	for _, host := range append(missing, current.DNSNames...) {
+		if !seen[host] && defaults.VerifyHostname(host) != nil {
+			seen[host] = true
+			extra = append(extra, host)
+		}
+	}
+
+	return true, addKeyPair(data, "apiserver", c.servingCertificateTemplate(extra...), caCert, caKey)
+}
+
+// parseCertificate decodes a PEM encoded certificate
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("certificate isn't PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// addKeyPair signs a new key for the template and stores both as <name>.crt and <name>.key
+func addKeyPair(files map[string][]byte, name string, template *x509.Certificate, ca *x509.Certificate, caKey crypto.Signer) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	_, certPEM, err := signCertificate(template, key, ca, caKey)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	files[name+".crt"] = certPEM
+	files[name+".key"] = keyPEM
+	return nil
+}
+
+// signCertificate signs the template for key with the CA, or self-signs it when ca is nil
+func signCertificate(template *x509.Certificate, key *ecdsa.PrivateKey, ca *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, []byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(pkiValidity)
+	template.KeyUsage |= x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	if ca == nil {
+		ca = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// kubeconfig renders a kubeconfig that authenticates with a client certificate
+func kubeconfig(server, user string, caPEM, certPEM, keyPEM []byte) ([]byte, error) {
+	config := clientcmdapi.Config{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []clientcmdapi.NamedCluster{{
+			Name: "kaas",
+			Cluster: clientcmdapi.Cluster{
+				Server:                   server,
+				CertificateAuthorityData: caPEM,
+			},
+		}},
+		AuthInfos: []clientcmdapi.NamedAuthInfo{{
+			Name: user,
+			AuthInfo: clientcmdapi.AuthInfo{
+				ClientCertificateData: certPEM,
+				ClientKeyData:         keyPEM,
+			},
+		}},
+		Contexts: []clientcmdapi.NamedContext{{
+			Name: "kaas",
+			Context: clientcmdapi.Context{
+				Cluster:  "kaas",
+				AuthInfo: user,
+			},
+		}},
+		CurrentContext: "kaas",
+	}
+
+	return yaml.Marshal(config)
+}
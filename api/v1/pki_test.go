@@ -0,0 +1,107 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+)
+
+func TestEnsureServingCertificate(t *testing.T) {
+	tests := []struct {
+		name         string
+		previous     []string
+		hosts        []string
+		wantReissued bool
+		wantHosts    []string
+	}{
+		{
+			name:      "already covered",
+			hosts:     []string{"127.0.0.1", "test.default.svc", ""},
+			wantHosts: []string{"127.0.0.1", "test.default.svc"},
+		},
+		{
+			name:         "ClusterIP and endpoint",
+			hosts:        []string{"10.100.23.7", "192.168.1.20"},
+			wantReissued: true,
+			wantHosts:    []string{"10.100.23.7", "192.168.1.20", "kubernetes.default.svc", "test"},
+		},
+		{
+			name:         "endpoint moved",
+			previous:     []string{"10.100.23.7", "192.168.1.20"},
+			hosts:        []string{"10.100.23.7", "192.168.1.21"},
+			wantReissued: true,
+			wantHosts:    []string{"10.100.23.7", "192.168.1.20", "192.168.1.21"},
+		},
+		{
+			name:         "hostname endpoint",
+			hosts:        []string{"lb.example.com"},
+			wantReissued: true,
+			wantHosts:    []string{"lb.example.com", "localhost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Name = "test"
+			c.Namespace = "default"
+
+			data, err := controlPlanePKI(c)
+			if err != nil {
+				t.Fatalf("controlPlanePKI() error = %v", err)
+			}
+			if _, err := c.EnsureServingCertificate(data, tt.previous...); err != nil {
+				t.Fatalf("EnsureServingCertificate(%v) error = %v", tt.previous, err)
+			}
+			before := data["apiserver.crt"]
+
+			reissued, err := c.EnsureServingCertificate(data, tt.hosts...)
+			if err != nil {
+				t.Fatalf("EnsureServingCertificate() error = %v", err)
+			}
+			if reissued != tt.wantReissued || reissued == bytes.Equal(before, data["apiserver.crt"]) {
+				t.Errorf("EnsureServingCertificate() = %v, want %v", reissued, tt.wantReissued)
+			}
+
+			cert, err := parseCertificate(data["apiserver.crt"])
+			if err != nil {
+				t.Fatalf("parseCertificate() error = %v", err)
+			}
+			ca, err := parseCertificate(data["ca.crt"])
+			if err != nil {
+				t.Fatalf("parseCertificate() error = %v", err)
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(ca)
+			for _, host := range tt.wantHosts {
+				if _, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+					t.Errorf("apiserver.crt isn't valid for %s: %v", host, err)
+				}
+			}
+		})
+	}
+
+	t.Run("no API server certificate", func(t *testing.T) {
+		data := map[string][]byte{"token": []byte("secret")}
+		reissued, err := (Cluster{}).EnsureServingCertificate(data, "10.100.23.7")
+		if reissued || err != nil || len(data) != 1 {
+			t.Errorf("EnsureServingCertificate() = %v, %v, left %d keys, want false, nil, 1", reissued, err, len(data))
+		}
+	})
+}
@@ -0,0 +1,139 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Provisioner holds everything specific to one ClusterType. By default the nested
+// cluster is created inside a privileged Docker-in-Docker pod: the Provisioner supplies
+// the commands to run there, and the Cluster takes care of the rest (service account
+// kubeconfig, clusterYAML manifests, progress markers).
+// +kubebuilder:object:generate=false
+type Provisioner interface {
+	// DefaultImage is the image used when neither spec.image nor the KaasConfig set one
+	DefaultImage() string
+	// Validate checks the type-specific parts of the Cluster's spec
+	Validate(c Cluster) field.ErrorList
+	// ConfigFiles returns extra files mounted into the Cluster pod at /honk, keyed by file name
+	ConfigFiles(c Cluster) (map[string]string, error)
+	// BootstrapCommand is the shell command that creates the nested cluster and
+	// leaves its admin kubeconfig at /root/.kube/config
+	BootstrapCommand(c Cluster) string
+	// ReadinessCheck is the shell script the Cluster pod's readiness probe runs
+	ReadinessCheck(c Cluster) string
+	// KubeconfigPaths maps the keys of the kubeconfig Secret to files inside the Cluster pod
+	KubeconfigPaths(c Cluster) map[string]string
+	// TeardownCommand is the shell command that deletes the nested cluster, or "" if there's nothing to clean up
+	TeardownCommand(c Cluster) string
+	// ExportLogsCommand is the shell command that writes the nested cluster's logs into dir, or "" if it can't
+	ExportLogsCommand(c Cluster, dir string) string
+}
+
+// PodBuilder is implemented by Provisioners that don't run in the default
+// Docker-in-Docker pod. BootstrapCommand and ReadinessCheck are then up to the PodSpec.
+// +kubebuilder:object:generate=false
+type PodBuilder interface {
+	PodSpec(c Cluster) v1.PodSpec
+}
+
+// SecretBuilder is implemented by Provisioners that need generated credentials, such as
+// certificates or join tokens. The controller stores them in the Cluster's bootstrap
+// Secret before creating the Cluster pod, and never regenerates them.
+// +kubebuilder:object:generate=false
+type SecretBuilder interface {
+	BootstrapSecretData(c Cluster) (map[string][]byte, error)
+}
+
+// AgentBuilder is implemented by Provisioners whose worker nodes run in pods of their own,
+// spec.workers of them next to the Cluster pod, so a nested cluster can span several host
+// nodes. Agent pods reach the nested API server through the Cluster's Service.
+// +kubebuilder:object:generate=false
+type AgentBuilder interface {
+	AgentPodSpec(c Cluster) v1.PodSpec
+}
+
+// NodeManager is implemented by Provisioners whose nodes are plain Node objects, like kwok's
+// fake nodes. The controller keeps Workers of them by running NodesCommand in the Cluster pod
+// once it has bootstrapped, so changing spec.workers doesn't recreate the pod.
+// +kubebuilder:object:generate=false
+type NodeManager interface {
+	// Workers is how many nodes the nested cluster should have
+	Workers(c Cluster) int32
+	// NodesCommand is the shell command that creates and deletes nodes until there are Workers of them
+	NodesCommand(c Cluster) string
+}
+
+// Overrider is implemented by Provisioners that replace parts of the user's config with
+// values kaas depends on, so the Cluster can report them in status.overriddenFields
+// +kubebuilder:object:generate=false
+type Overrider interface {
+	OverriddenFields(c Cluster) []string
+}
+
+// ToolInstaller is implemented by Provisioners whose bootstrap command downloads tools with
+// Cluster.InstallTool, so their versions can be pinned through spec.tools
+// +kubebuilder:object:generate=false
+type ToolInstaller interface {
+	Tools() []string
+}
+
+var (
+	provisionersMu sync.RWMutex
+	provisioners   = make(map[ClusterType]Provisioner)
+)
+
+// RegisterProvisioner makes a ClusterType available to Clusters, usually from an init function.
+// Registering the same type twice replaces the earlier Provisioner.
+func RegisterProvisioner(clusterType ClusterType, provisioner Provisioner) {
+	provisionersMu.Lock()
+	defer provisionersMu.Unlock()
+	provisioners[clusterType] = provisioner
+}
+
+// ProvisionerFor returns the Provisioner registered for a ClusterType
+func ProvisionerFor(clusterType ClusterType) (Provisioner, bool) {
+	provisionersMu.RLock()
+	defer provisionersMu.RUnlock()
+	provisioner, ok := provisioners[clusterType]
+	return provisioner, ok
+}
+
+// ClusterTypes lists every registered ClusterType, sorted
+func ClusterTypes() []string {
+	provisionersMu.RLock()
+	defer provisionersMu.RUnlock()
+	var types []string
+	for clusterType := range provisioners {
+		types = append(types, string(clusterType))
+	}
+	sort.Strings(types)
+	return types
+}
+
+// defaultKubeconfigPaths are where the Docker-in-Docker bootstrap leaves the admin and service account kubeconfigs
+func defaultKubeconfigPaths() map[string]string {
+	return map[string]string{
+		"root-config":    "/root/.kube/config",
+		"default-config": "/tmp/kube/k8s-kind-user-default-conf",
+	}
+}
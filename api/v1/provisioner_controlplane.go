@@ -0,0 +1,78 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(ControlPlaneCluster, controlPlaneProvisioner{})
+}
+
+// controlPlaneProvisioner runs just etcd and kube-apiserver, plus kube-controller-manager
+// when spec.controllerManager is set, for API-level testing. There are no nodes.
+// spec.image is the Kubernetes version.
+// +kubebuilder:object:generate=false
+type controlPlaneProvisioner struct{}
+
+func (controlPlaneProvisioner) DefaultImage() string {
+	return DefaultControlPlaneImage
+}
+
+func (controlPlaneProvisioner) Validate(c Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.Spec.Image != "" && !kubernetesVersion.MatchString(c.Spec.Image) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "image"), c.Spec.Image, "must be a Kubernetes version such as v1.18.0 for controlplane clusters"))
+	}
+	return allErrs
+}
+
+func (controlPlaneProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	return nil, nil
+}
+
+func (controlPlaneProvisioner) BootstrapCommand(c Cluster) string {
+	return ""
+}
+
+func (controlPlaneProvisioner) ReadinessCheck(c Cluster) string {
+	return readinessCheck(false)
+}
+
+func (p controlPlaneProvisioner) PodSpec(c Cluster) v1.PodSpec {
+	return c.controlPlanePodSpec(p.BootstrapCommand(c), p.ReadinessCheck(c), c.Spec.ControllerManager)
+}
+
+func (controlPlaneProvisioner) BootstrapSecretData(c Cluster) (map[string][]byte, error) {
+	return controlPlanePKI(c)
+}
+
+// KubeconfigPaths only includes the service account kubeconfig when the
+// controller manager is running to mint its token
+func (controlPlaneProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return controlPlaneKubeconfigPaths(c.Spec.ControllerManager)
+}
+
+func (controlPlaneProvisioner) TeardownCommand(c Cluster) string {
+	return ""
+}
+
+func (controlPlaneProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return ""
+}
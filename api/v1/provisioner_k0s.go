@@ -0,0 +1,129 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(K0sCluster, k0sProvisioner{})
+}
+
+// k0sProvisioner runs a k0s controller+worker node as a container inside the Cluster pod
+// +kubebuilder:object:generate=false
+type k0sProvisioner struct{}
+
+func (k0sProvisioner) DefaultImage() string {
+	return DefaultK0sImage
+}
+
+// Validate checks that spec.clusterSpec, if set, is a k0s ClusterConfig
+func (k0sProvisioner) Validate(c Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.Spec.ClusterSpec == "" {
+		return allErrs
+	}
+
+	path := field.NewPath("spec", "clusterSpec")
+	var config struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+	}
+	if err := yaml.Unmarshal([]byte(c.Spec.ClusterSpec), &config); err != nil {
+		allErrs = append(allErrs, field.Invalid(path, c.Spec.ClusterSpec, fmt.Sprintf("must be a valid k0s ClusterConfig: %s", err.Error())))
+	} else if !strings.HasPrefix(config.APIVersion, "k0s.k0sproject.io/") || config.Kind != "ClusterConfig" {
+		allErrs = append(allErrs, field.Invalid(path, c.Spec.ClusterSpec, "must be a k0s.k0sproject.io ClusterConfig"))
+	}
+	return allErrs
+}
+
+// k0sAPIAddress is where the admin kubeconfig points inside the Cluster pod
+const k0sAPIAddress = "0.0.0.0"
+
+func (k0sProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	config, err := c.k0sConfig()
+	return map[string]string{"k0s.yaml": config}, err
+}
+
+// k0sConfig renders spec.clusterSpec, or a default ClusterConfig, with k0sAPIAddress added to
+// spec.api.sans. Otherwise kubectl in the Cluster pod can't verify the API server certificate.
+func (c Cluster) k0sConfig() (string, error) {
+	config := map[string]interface{}{
+		"apiVersion": "k0s.k0sproject.io/v1beta1",
+		"kind":       "ClusterConfig",
+		"metadata":   map[string]interface{}{"name": "k0s"},
+	}
+	if c.Spec.ClusterSpec != "" {
+		config = map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(c.Spec.ClusterSpec), &config); err != nil {
+			return "", err
+		}
+	}
+
+	api := yamlMap(yamlMap(config, "spec"), "api")
+	sans, _ := api["sans"].([]interface{})
+	found := false
+	for _, san := range sans {
+		found = found || san == k0sAPIAddress
+	}
+	if !found {
+		api["sans"] = append(sans, k0sAPIAddress)
+	}
+
+	data, err := yaml.Marshal(config)
+	return string(data), err
+}
+
+// yamlMap returns the mapping under key, replacing whatever else is there with an empty one
+func yamlMap(parent map[string]interface{}, key string) map[string]interface{} {
+	child, ok := parent[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		parent[key] = child
+	}
+	return child
+}
+
+// BootstrapCommand starts k0s, waits for its API server and then copies the admin
+// kubeconfig out, pointing it at 0.0.0.0:6443 like kind so the controller can rewrite it
+func (k0sProvisioner) BootstrapCommand(c Cluster) string {
+	return fmt.Sprintf("docker run -d --name k0s --hostname k0s --privileged -v /var/lib/k0s -v /honk:/honk:ro -p 6443:6443 %s k0s controller --enable-worker --config=/honk/k0s.yaml && "+
+		"until docker exec k0s k0s kubeconfig admin > /root/.kube/config 2>/dev/null; do sleep 5; done && "+
+		"sed -i -E 's#server: https://.*#server: https://%s:6443#' /root/.kube/config && "+
+		"until kubectl get --raw /readyz >/dev/null 2>&1; do sleep 5; done", c.Image(), k0sAPIAddress)
+}
+
+func (k0sProvisioner) ReadinessCheck(c Cluster) string {
+	return DefaultReadinessCheck
+}
+
+func (k0sProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return defaultKubeconfigPaths()
+}
+
+func (k0sProvisioner) TeardownCommand(c Cluster) string {
+	return "docker rm -f k0s"
+}
+
+func (k0sProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return fmt.Sprintf("mkdir -p %s && docker logs k0s > %s/k0s.log 2>&1", dir, dir)
+}
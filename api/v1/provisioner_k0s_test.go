@@ -0,0 +1,94 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestK0sConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		clusterSpec string
+		wantSANs    []string
+		wantPort    int
+	}{
+		{
+			name:     "default config",
+			wantSANs: []string{"0.0.0.0"},
+		},
+		{
+			name:        "sample manifest",
+			clusterSpec: "apiVersion: k0s.k0sproject.io/v1beta1\nkind: ClusterConfig\nmetadata:\n  name: k0s\nspec:\n  api:\n    port: 6443\n",
+			wantSANs:    []string{"0.0.0.0"},
+			wantPort:    6443,
+		},
+		{
+			name:        "keeps the user's sans",
+			clusterSpec: "apiVersion: k0s.k0sproject.io/v1beta1\nkind: ClusterConfig\nspec:\n  api:\n    sans: [k0s.example.com]\n",
+			wantSANs:    []string{"k0s.example.com", "0.0.0.0"},
+		},
+		{
+			name:        "already has 0.0.0.0",
+			clusterSpec: "apiVersion: k0s.k0sproject.io/v1beta1\nkind: ClusterConfig\nspec:\n  api:\n    sans: [0.0.0.0]\n",
+			wantSANs:    []string{"0.0.0.0"},
+		},
+		{
+			name:        "empty spec",
+			clusterSpec: "apiVersion: k0s.k0sproject.io/v1beta1\nkind: ClusterConfig\nspec:\n",
+			wantSANs:    []string{"0.0.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = K0sCluster
+			c.Spec.ClusterSpec = tt.clusterSpec
+
+			data, err := c.k0sConfig()
+			if err != nil {
+				t.Fatalf("k0sConfig() error = %v", err)
+			}
+			var got struct {
+				APIVersion string `yaml:"apiVersion"`
+				Kind       string `yaml:"kind"`
+				Spec       struct {
+					API struct {
+						Port int      `yaml:"port"`
+						SANs []string `yaml:"sans"`
+					} `yaml:"api"`
+				} `yaml:"spec"`
+			}
+			if err := yaml.Unmarshal([]byte(data), &got); err != nil {
+				t.Fatalf("k0sConfig() = %q, not valid YAML: %v", data, err)
+			}
+			if got.APIVersion != "k0s.k0sproject.io/v1beta1" || got.Kind != "ClusterConfig" {
+				t.Errorf("k0sConfig() is a %s %s, want a k0s.k0sproject.io/v1beta1 ClusterConfig", got.APIVersion, got.Kind)
+			}
+			if !reflect.DeepEqual(got.Spec.API.SANs, tt.wantSANs) {
+				t.Errorf("k0sConfig() spec.api.sans = %v, want %v", got.Spec.API.SANs, tt.wantSANs)
+			}
+			if got.Spec.API.Port != tt.wantPort {
+				t.Errorf("k0sConfig() spec.api.port = %d, want %d", got.Spec.API.Port, tt.wantPort)
+			}
+		})
+	}
+}
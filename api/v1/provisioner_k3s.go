@@ -0,0 +1,71 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(K3sCluster, k3sProvisioner{})
+}
+
+// k3sProvisioner runs a k3s cluster through k3d inside the Cluster pod, configured by spec.k3s
+// +kubebuilder:object:generate=false
+type k3sProvisioner struct{}
+
+func (k3sProvisioner) DefaultImage() string {
+	return DefaultK3sImage
+}
+
+func (k3sProvisioner) Validate(c Cluster) field.ErrorList {
+	if c.Spec.K3s == nil {
+		return nil
+	}
+	return c.Spec.K3s.validate(field.NewPath("spec", "k3s"), true)
+}
+
+func (k3sProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	k3dConfig, err := c.K3dConfig()
+	return map[string]string{"k3d-config.yaml": k3dConfig}, err
+}
+
+func (k3sProvisioner) BootstrapCommand(c Cluster) string {
+	return c.InstallTool(ToolK3d) + fmt.Sprintf(" && k3d cluster create --config /honk/k3d-config.yaml && k3d kubeconfig get %s | sed -E 's#https://[^/]+:6443#https://0.0.0.0:6443#' > /root/.kube/config", k3dClusterName)
+}
+
+func (k3sProvisioner) Tools() []string {
+	return []string{ToolK3d}
+}
+
+func (k3sProvisioner) ReadinessCheck(c Cluster) string {
+	return DefaultReadinessCheck
+}
+
+func (k3sProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return defaultKubeconfigPaths()
+}
+
+func (k3sProvisioner) TeardownCommand(c Cluster) string {
+	return fmt.Sprintf("k3d cluster delete %s", k3dClusterName)
+}
+
+func (k3sProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return fmt.Sprintf("mkdir -p %s && for id in $(docker ps -aq); do docker logs $id > %s/$(docker inspect -f '{{.Name}}' $id | tr -d /).log 2>&1; done", dir, dir)
+}
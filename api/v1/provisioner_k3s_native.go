@@ -0,0 +1,247 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(K3sNativeCluster, k3sNativeProvisioner{})
+}
+
+const (
+	// k3sDataDir is where k3s keeps its state, on an emptyDir since containerd can't run on overlayfs
+	k3sDataDir = "/var/lib/rancher/k3s"
+	// k3sTokenKey is the key of the join token in the bootstrap Secret
+	k3sTokenKey = "token"
+	// k3sRegistriesFile is where k3s reads its registry configuration from
+	k3sRegistriesFile = "/etc/rancher/k3s/registries.yaml"
+)
+
+// k3sNativeProvisioner runs the k3s server directly as a container of the Cluster pod, without
+// Docker-in-Docker or k3d, and spec.workers k3s agents in pods of their own that join it
+// with a generated token. spec.image is the rancher/k3s image, spec.k3s configures both.
+// +kubebuilder:object:generate=false
+type k3sNativeProvisioner struct{}
+
+func (k3sNativeProvisioner) DefaultImage() string {
+	return DefaultK3sImage
+}
+
+func (k3sNativeProvisioner) Validate(c Cluster) field.ErrorList {
+	if c.Spec.K3s == nil {
+		return nil
+	}
+	// The server and agents run straight in their pods, there's nothing to mount volumes from
+	return c.Spec.K3s.validate(field.NewPath("spec", "k3s"), false)
+}
+
+// ConfigFiles adds the registries.yaml mounted into the server and agents
+func (k3sNativeProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	if c.Spec.K3s == nil || c.Spec.K3s.Registries == nil {
+		return nil, nil
+	}
+	registries, err := c.Spec.K3s.RegistriesYAML()
+	return map[string]string{"registries.yaml": registries}, err
+}
+
+// BootstrapCommand is empty, the k3s server container creates the cluster by itself
+func (k3sNativeProvisioner) BootstrapCommand(c Cluster) string {
+	return ""
+}
+
+func (k3sNativeProvisioner) ReadinessCheck(c Cluster) string {
+	return DefaultReadinessCheck
+}
+
+// PodSpec runs the k3s server, which writes its kubeconfig to a volume shared with the
+// bootstrap container. The server also runs the Cluster pod's node.
+func (p k3sNativeProvisioner) PodSpec(c Cluster) v1.PodSpec {
+	falseValue := false
+	kubeconfigMount := v1.VolumeMount{
+		Name:      "kubeconfig",
+		MountPath: controlPlaneKubeconfigDir,
+	}
+	// k3s writes a kubeconfig for 127.0.0.1, point it at 0.0.0.0 like every other cluster type
+	prepare := fmt.Sprintf("until [ -s %[1]s/k3s.yaml ]; do sleep 1; done && sed 's#https://127.0.0.1:6443#https://0.0.0.0:6443#' %[1]s/k3s.yaml > %[1]s/config", controlPlaneKubeconfigDir)
+
+	command := []string{
+		"k3s",
+		"server",
+		"--https-listen-port=6443",
+		"--write-kubeconfig=" + controlPlaneKubeconfigDir + "/k3s.yaml",
+		"--write-kubeconfig-mode=644",
+		"--tls-san=0.0.0.0",
+		"--tls-san=" + c.Name,
+		fmt.Sprintf("--tls-san=%s.%s", c.Name, c.Namespace),
+		fmt.Sprintf("--tls-san=%s.%s.svc", c.Name, c.Namespace),
+	}
+	if c.Spec.K3s != nil {
+		command = append(command, c.Spec.K3s.serverArgs()...)
+	}
+	server := k3sContainer(c, "k3s-server", command)
+	server.VolumeMounts = append(server.VolumeMounts, kubeconfigMount)
+
+	return v1.PodSpec{
+		AutomountServiceAccountToken: &falseValue,
+		EnableServiceLinks:           &falseValue,
+		// A failed bootstrap should fail the pod rather than crash-loop, the controller decides whether to retry
+		RestartPolicy: v1.RestartPolicyNever,
+		Containers: []v1.Container{
+			c.bootstrapContainer(prepare, p.BootstrapCommand(c), p.ReadinessCheck(c), true, kubeconfigMount),
+			server,
+		},
+		Volumes: []v1.Volume{
+			{
+				Name: "kubeconfig",
+				VolumeSource: v1.VolumeSource{
+					EmptyDir: &v1.EmptyDirVolumeSource{},
+				},
+			},
+			{
+				Name: "k3s-data",
+				VolumeSource: v1.VolumeSource{
+					EmptyDir: &v1.EmptyDirVolumeSource{},
+				},
+			},
+			{
+				Name: "honk",
+				VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: c.Name,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// AgentPodSpec runs a k3s agent that joins the server through the Cluster's Service.
+// Agents restart until the server is reachable, the controller replaces them along with the Cluster pod.
+func (k3sNativeProvisioner) AgentPodSpec(c Cluster) v1.PodSpec {
+	falseValue := false
+	command := []string{"k3s", "agent"}
+	if c.Spec.K3s != nil {
+		command = append(command, c.Spec.K3s.AgentArgs...)
+	}
+	agent := k3sContainer(c, "k3s-agent", command)
+	agent.Env = append(agent.Env, v1.EnvVar{
+		Name:  "K3S_URL",
+		Value: fmt.Sprintf("https://%s.%s.svc:%d", c.Name, c.Namespace, c.servicePort().Port),
+	})
+
+	volumes := []v1.Volume{
+		{
+			Name: "k3s-data",
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		},
+	}
+	// The registries.yaml lives in the Cluster's ConfigMap
+	if c.Spec.K3s != nil && c.Spec.K3s.Registries != nil {
+		volumes = append(volumes, v1.Volume{
+			Name: "honk",
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: c.Name,
+					},
+				},
+			},
+		})
+	}
+
+	return v1.PodSpec{
+		AutomountServiceAccountToken: &falseValue,
+		EnableServiceLinks:           &falseValue,
+		RestartPolicy:                v1.RestartPolicyAlways,
+		Containers:                   []v1.Container{agent},
+		Volumes:                      volumes,
+	}
+}
+
+// BootstrapSecretData generates the token agents join the server with
+func (k3sNativeProvisioner) BootstrapSecretData(c Cluster) (map[string][]byte, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return map[string][]byte{k3sTokenKey: []byte(hex.EncodeToString(token))}, nil
+}
+
+func (k3sNativeProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return controlPlaneKubeconfigPaths(true)
+}
+
+func (k3sNativeProvisioner) TeardownCommand(c Cluster) string {
+	return ""
+}
+
+func (k3sNativeProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return ""
+}
+
+// k3sContainer is a privileged k3s container, as both servers and agents run containerd and a kubelet
+func k3sContainer(c Cluster, name string, command []string) v1.Container {
+	trueValue := true
+	mounts := []v1.VolumeMount{
+		{
+			Name:      "k3s-data",
+			MountPath: k3sDataDir,
+		},
+	}
+	if c.Spec.K3s != nil && c.Spec.K3s.Registries != nil {
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      "honk",
+			MountPath: k3sRegistriesFile,
+			SubPath:   "registries.yaml",
+		})
+	}
+
+	return v1.Container{
+		Name:    name,
+		Image:   c.Image(),
+		Command: command,
+		Env: []v1.EnvVar{
+			{
+				Name: "K3S_TOKEN",
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{
+							Name: c.BootstrapSecretName(),
+						},
+						Key: k3sTokenKey,
+					},
+				},
+			},
+		},
+		SecurityContext: &v1.SecurityContext{
+			Privileged: &trueValue,
+		},
+		VolumeMounts: mounts,
+		Resources:    c.resources(),
+	}
+}
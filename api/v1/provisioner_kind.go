@@ -0,0 +1,88 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(KindCluster, kindProvisioner{})
+}
+
+// kindProvisioner runs a sigs.k8s.io/kind cluster inside the Cluster pod, configured by spec.kind or spec.clusterSpec
+// +kubebuilder:object:generate=false
+type kindProvisioner struct{}
+
+func (kindProvisioner) DefaultImage() string {
+	return DefaultKindImage
+}
+
+func (kindProvisioner) Validate(c Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if c.Spec.Kind != nil {
+		if c.Spec.ClusterSpec != "" {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("clusterSpec"), "can't be combined with spec.kind"))
+		}
+		allErrs = append(allErrs, c.Spec.Kind.validate(specPath.Child("kind"))...)
+	}
+	if _, err := c.KindConfig(); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "clusterSpec"), c.Spec.ClusterSpec, fmt.Sprintf("must be a valid kind v1alpha4 Cluster: %s", err.Error())))
+	}
+	return allErrs
+}
+
+// OverriddenFields lists the API server address and port and node images kaas replaces
+func (kindProvisioner) OverriddenFields(c Cluster) []string {
+	kindConfig, err := c.parseKindConfig()
+	if err != nil {
+		return nil
+	}
+	return c.kindOverrides(kindConfig)
+}
+
+func (kindProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	kindConfig, err := c.KindConfig()
+	return map[string]string{"kind-config.yaml": kindConfig}, err
+}
+
+func (kindProvisioner) BootstrapCommand(c Cluster) string {
+	return c.InstallTool(ToolKind) + fmt.Sprintf(" && kind create cluster --image %s --config=/honk/kind-config.yaml", c.Image())
+}
+
+func (kindProvisioner) Tools() []string {
+	return []string{ToolKind}
+}
+
+func (kindProvisioner) ReadinessCheck(c Cluster) string {
+	return DefaultReadinessCheck
+}
+
+func (kindProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return defaultKubeconfigPaths()
+}
+
+func (kindProvisioner) TeardownCommand(c Cluster) string {
+	return "kind delete cluster"
+}
+
+func (kindProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return fmt.Sprintf("kind export logs %s >/dev/null", dir)
+}
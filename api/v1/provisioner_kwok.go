@@ -0,0 +1,137 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(KwokCluster, kwokProvisioner{})
+}
+
+const kwokImage = "registry.k8s.io/kwok/kwok:v0.1.0"
+
+// kwokProvisioner runs a control plane whose nodes are faked by kwok, so no kubelets
+// or Docker-in-Docker are needed. spec.image is the Kubernetes version and
+// spec.workers the number of fake nodes.
+// +kubebuilder:object:generate=false
+type kwokProvisioner struct{}
+
+func (kwokProvisioner) DefaultImage() string {
+	return DefaultKwokImage
+}
+
+func (kwokProvisioner) Validate(c Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if c.Spec.Image != "" && !kubernetesVersion.MatchString(c.Spec.Image) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("image"), c.Spec.Image, "must be a Kubernetes version such as v1.18.0 for kwok clusters"))
+	}
+	if c.Spec.Workers != nil && *c.Spec.Workers < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("workers"), *c.Spec.Workers, "kwok clusters need at least one fake node"))
+	}
+	return allErrs
+}
+
+func (kwokProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	return nil, nil
+}
+
+// BootstrapCommand has nothing to add, the controller creates the fake nodes
+func (kwokProvisioner) BootstrapCommand(c Cluster) string {
+	return ""
+}
+
+func (kwokProvisioner) ReadinessCheck(c Cluster) string {
+	return DefaultReadinessCheck
+}
+
+func (p kwokProvisioner) PodSpec(c Cluster) v1.PodSpec {
+	return c.controlPlanePodSpec(p.BootstrapCommand(c), p.ReadinessCheck(c), c.Spec.ControllerManager, v1.Container{
+		Name:  "kwok-controller",
+		Image: kwokImage,
+		Command: []string{
+			"kwok",
+			"--kubeconfig=" + pkiDir + "/admin.conf",
+			"--manage-all-nodes=false",
+			"--manage-nodes-with-annotation-selector=kwok.x-k8s.io/node=fake",
+		},
+	})
+}
+
+func (kwokProvisioner) Workers(c Cluster) int32 {
+	if c.Spec.Workers != nil {
+		return *c.Spec.Workers
+	}
+	return DefaultKwokWorkers
+}
+
+// NodesCommand applies the fake nodes kwok-node-0 to kwok-node-<workers-1> and deletes the rest
+func (p kwokProvisioner) NodesCommand(c Cluster) string {
+	workers := p.Workers(c)
+
+	var nodes []string
+	for i := int32(0); i < workers; i++ {
+		nodes = append(nodes, fmt.Sprintf(`apiVersion: v1
+kind: Node
+metadata:
+  name: kwok-node-%d
+  annotations:
+    node.alpha.kubernetes.io/ttl: "0"
+    kwok.x-k8s.io/node: fake
+  labels:
+    kubernetes.io/arch: amd64
+    kubernetes.io/hostname: kwok-node-%d
+    kubernetes.io/os: linux
+    kubernetes.io/role: agent
+    node-role.kubernetes.io/agent: ""
+    type: kwok
+`, i, i))
+	}
+
+	return strings.Join([]string{
+		"set -e",
+		"kubectl apply -f - >/dev/null <<'EOF'",
+		strings.Join(nodes, "---\n") + "EOF",
+		"for node in $(kubectl get nodes -l type=kwok -o name); do",
+		fmt.Sprintf(`  [ "${node#node/kwok-node-}" -lt %d ] 2>/dev/null || kubectl delete "$node" >/dev/null`, workers),
+		"done",
+	}, "\n")
+}
+
+func (kwokProvisioner) BootstrapSecretData(c Cluster) (map[string][]byte, error) {
+	return controlPlanePKI(c)
+}
+
+// KubeconfigPaths only includes the service account kubeconfig when the
+// controller manager is running to mint its token
+func (kwokProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return controlPlaneKubeconfigPaths(c.Spec.ControllerManager)
+}
+
+func (kwokProvisioner) TeardownCommand(c Cluster) string {
+	return ""
+}
+
+func (kwokProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return ""
+}
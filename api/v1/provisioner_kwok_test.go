@@ -0,0 +1,128 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKwokControllerManager(t *testing.T) {
+	for _, controllerManager := range []bool{false, true} {
+		c := Cluster{}
+		c.Spec.ClusterType = KwokCluster
+		c.Spec.ControllerManager = controllerManager
+
+		found := false
+		for _, container := range (kwokProvisioner{}).PodSpec(c).Containers {
+			found = found || container.Name == "kube-controller-manager"
+		}
+		if found != controllerManager {
+			t.Errorf("controllerManager: %v, pod runs kube-controller-manager: %v", controllerManager, found)
+		}
+
+		_, serviceAccount := (kwokProvisioner{}).KubeconfigPaths(c)["default-config"]
+		if serviceAccount != controllerManager {
+			t.Errorf("controllerManager: %v, service account kubeconfig: %v", controllerManager, serviceAccount)
+		}
+	}
+}
+
+// stubKubectl is a kubectl that records how it was called and has four kwok nodes
+const stubKubectl = `#!/bin/sh
+echo "$@" >>"$STUB_DIR/calls"
+case "$1" in
+apply) cat >"$STUB_DIR/applied" ;;
+get) printf 'node/kwok-node-0\nnode/kwok-node-1\nnode/kwok-node-2\nnode/kwok-node-3\n' ;;
+esac
+`
+
+func TestKwokNodesCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		workers     *int32
+		wantApplied int
+		wantDeleted []string
+	}{
+		{
+			name:        "default",
+			wantApplied: DefaultKwokWorkers,
+			wantDeleted: []string{"delete node/kwok-node-1", "delete node/kwok-node-2", "delete node/kwok-node-3"},
+		},
+		{
+			name:        "scale down",
+			workers:     int32Ptr(2),
+			wantApplied: 2,
+			wantDeleted: []string{"delete node/kwok-node-2", "delete node/kwok-node-3"},
+		},
+		{
+			name:        "scale up",
+			workers:     int32Ptr(6),
+			wantApplied: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "kwok")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			if err := ioutil.WriteFile(filepath.Join(dir, "kubectl"), []byte(stubKubectl), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			c := Cluster{}
+			c.Spec.ClusterType = KwokCluster
+			c.Spec.Workers = tt.workers
+			if workers, ok := c.ManagedNodes(); !ok || int(workers) != tt.wantApplied {
+				t.Errorf("ManagedNodes() = %d, %v, want %d, true", workers, ok, tt.wantApplied)
+			}
+
+			cmd := exec.Command("sh", "-c", (kwokProvisioner{}).NodesCommand(c))
+			cmd.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"), "STUB_DIR="+dir)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("NodesCommand() failed: %v: %s", err, output)
+			}
+
+			applied, _ := ioutil.ReadFile(filepath.Join(dir, "applied"))
+			if got := strings.Count(string(applied), "kind: Node"); got != tt.wantApplied {
+				t.Errorf("applied %d nodes, want %d:\n%s", got, tt.wantApplied, applied)
+			}
+			if err := validateManifest(string(applied)); err != nil {
+				t.Errorf("applied an invalid manifest: %v", err)
+			}
+
+			calls, _ := ioutil.ReadFile(filepath.Join(dir, "calls"))
+			var deleted []string
+			for _, call := range strings.Split(strings.TrimSpace(string(calls)), "\n") {
+				if strings.HasPrefix(call, "delete ") {
+					deleted = append(deleted, call)
+				}
+			}
+			if !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("deleted %v, want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func init() {
+	RegisterProvisioner(MinikubeCluster, minikubeProvisioner{})
+}
+
+// kubernetesVersion matches the versions minikube accepts for --kubernetes-version
+var kubernetesVersion = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+// minikubeProvisioner runs minikube with the docker driver inside the Cluster pod.
+// spec.image is the Kubernetes version rather than a node image.
+// +kubebuilder:object:generate=false
+type minikubeProvisioner struct{}
+
+func (minikubeProvisioner) DefaultImage() string {
+	return DefaultMinikubeImage
+}
+
+func (minikubeProvisioner) Validate(c Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.Spec.Image != "" && !kubernetesVersion.MatchString(c.Spec.Image) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "image"), c.Spec.Image, "must be a Kubernetes version such as v1.18.0 for minikube clusters"))
+	}
+	return allErrs
+}
+
+func (minikubeProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	return nil, nil
+}
+
+// BootstrapCommand publishes the API server on the pod's port 6443 and points the
+// admin kubeconfig at 0.0.0.0:6443, like kind, so the controller can rewrite it.
+// minikube only puts 0.0.0.0 in the API server certificate when asked to, without it
+// the readiness probe's kubectl fails to verify the certificate.
+func (minikubeProvisioner) BootstrapCommand(c Cluster) string {
+	return c.InstallTool(ToolMinikube) + fmt.Sprintf(" && minikube start --force --driver=docker --kubernetes-version=%s --apiserver-port=6443 --apiserver-ips=0.0.0.0 --listen-address=0.0.0.0 --ports=6443:6443 --embed-certs && kubectl config set-cluster minikube --server=https://0.0.0.0:6443", c.Image())
+}
+
+func (minikubeProvisioner) Tools() []string {
+	return []string{ToolMinikube}
+}
+
+func (minikubeProvisioner) ReadinessCheck(c Cluster) string {
+	return DefaultReadinessCheck
+}
+
+func (minikubeProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return defaultKubeconfigPaths()
+}
+
+func (minikubeProvisioner) TeardownCommand(c Cluster) string {
+	return "minikube delete"
+}
+
+func (minikubeProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return fmt.Sprintf("mkdir -p %s && minikube logs > %s/minikube.log 2>&1", dir, dir)
+}
@@ -0,0 +1,38 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMinikubeBootstrapCommand(t *testing.T) {
+	c := Cluster{}
+	c.Spec.ClusterType = MinikubeCluster
+	command := minikubeProvisioner{}.BootstrapCommand(c)
+
+	// kubectl in the pod, including the readiness probe, talks to the address in the
+	// kubeconfig, so it has to be one the API server certificate is valid for
+	server := regexp.MustCompile(`--server=https://([^:]+):6443`).FindStringSubmatch(command)
+	if server == nil {
+		t.Fatalf("BootstrapCommand() = %q, doesn't point the kubeconfig at port 6443", command)
+	}
+	if !regexp.MustCompile(`--apiserver-ips=(\S+,)?` + regexp.QuoteMeta(server[1]) + `(,|\s)`).MatchString(command) {
+		t.Errorf("BootstrapCommand() = %q, want %s in --apiserver-ips", command, server[1])
+	}
+}
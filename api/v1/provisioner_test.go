@@ -0,0 +1,140 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const testCluster ClusterType = "test"
+
+// testProvisioner is a Provisioner for clusters that don't exist
+type testProvisioner struct{}
+
+func (testProvisioner) DefaultImage() string {
+	return "example.com/test:v1"
+}
+
+func (testProvisioner) Validate(c Cluster) field.ErrorList {
+	if c.Spec.ClusterSpec == "" {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "clusterSpec"), "not supported")}
+}
+
+func (testProvisioner) ConfigFiles(c Cluster) (map[string]string, error) {
+	return map[string]string{"test.yaml": "nodes: 1\n"}, nil
+}
+
+func (testProvisioner) BootstrapCommand(c Cluster) string {
+	return "test-cluster create --image " + c.Image()
+}
+
+func (testProvisioner) ReadinessCheck(c Cluster) string {
+	return "test-cluster ready"
+}
+
+func (testProvisioner) KubeconfigPaths(c Cluster) map[string]string {
+	return defaultKubeconfigPaths()
+}
+
+func (testProvisioner) TeardownCommand(c Cluster) string {
+	return "test-cluster delete"
+}
+
+func (testProvisioner) ExportLogsCommand(c Cluster, dir string) string {
+	return ""
+}
+
+// registerTestProvisioner registers testProvisioner, returning a func that unregisters it
+func registerTestProvisioner() func() {
+	RegisterProvisioner(testCluster, testProvisioner{})
+	return func() {
+		provisionersMu.Lock()
+		defer provisionersMu.Unlock()
+		delete(provisioners, testCluster)
+	}
+}
+
+func TestProvisionerRegistry(t *testing.T) {
+	for _, clusterType := range []ClusterType{KindCluster, K3sCluster} {
+		if _, ok := ProvisionerFor(clusterType); !ok {
+			t.Errorf("ProvisionerFor(%s) isn't registered", clusterType)
+		}
+	}
+	if _, ok := ProvisionerFor(testCluster); ok {
+		t.Fatalf("ProvisionerFor(%s) is registered before RegisterProvisioner()", testCluster)
+	}
+
+	unregister := registerTestProvisioner()
+	defer unregister()
+
+	if provisioner, ok := ProvisionerFor(testCluster); !ok || provisioner != (testProvisioner{}) {
+		t.Errorf("ProvisionerFor(%s) = %v, %v, want the registered Provisioner", testCluster, provisioner, ok)
+	}
+	types := ClusterTypes()
+	if !sort.StringsAreSorted(types) {
+		t.Errorf("ClusterTypes() = %v, want them sorted", types)
+	}
+	for _, want := range []ClusterType{KindCluster, K3sCluster, testCluster} {
+		found := false
+		for _, clusterType := range types {
+			found = found || clusterType == string(want)
+		}
+		if !found {
+			t.Errorf("ClusterTypes() = %v, missing %s", types, want)
+		}
+	}
+}
+
+func TestProvisionerCluster(t *testing.T) {
+	unregister := registerTestProvisioner()
+	defer unregister()
+
+	c := validCluster()
+	c.Spec.ClusterType = testCluster
+
+	if got := c.Image(); got != "example.com/test:v1" {
+		t.Errorf("Image() = %q, want the Provisioner's default image", got)
+	}
+	if got := c.ConfigMap("default").Data["test.yaml"]; got != "nodes: 1\n" {
+		t.Errorf("ConfigMap() test.yaml = %q, want the Provisioner's config file", got)
+	}
+
+	container := c.Pod("default").Spec.Containers[0]
+	if command := container.Command[len(container.Command)-1]; !strings.Contains(command, "test-cluster create --image example.com/test:v1 && ") {
+		t.Errorf("Pod() command = %q, want the Provisioner's bootstrap command", command)
+	}
+	probe := container.ReadinessProbe.Exec.Command
+	if got := probe[len(probe)-1]; got != "test-cluster ready" {
+		t.Errorf("Pod() readiness probe = %q, want the Provisioner's readiness check", got)
+	}
+
+	var fields []string
+	c.Spec.ClusterSpec = "nodes: 1"
+	for _, err := range c.validateClusterSpec() {
+		fields = append(fields, err.Field)
+	}
+	if want := []string{"spec.clusterSpec"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("validateClusterSpec() fields = %v, want the Provisioner's %v", fields, want)
+	}
+}
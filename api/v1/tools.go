@@ -0,0 +1,227 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/rest"
+)
+
+// Bootstrap tools a Cluster pod can download, as used in spec.tools and status.toolVersions
+const (
+	ToolKind     = "kind"
+	ToolK3d      = "k3d"
+	ToolKubectl  = "kubectl"
+	ToolMinikube = "minikube"
+)
+
+var (
+	toolVersionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+	sha256Pattern      = regexp.MustCompile(`^[a-f0-9]{64}$`)
+)
+
+// bootstrapTool describes how a Cluster pod gets and reports a tool
+type bootstrapTool struct {
+	// latest installs the tool when it isn't pinned, or is empty when the pod's image already has it
+	latest string
+	// url is where a pinned version of the linux-amd64 binary is downloaded from
+	url string
+	// version prints the installed version
+	version string
+}
+
+var bootstrapTools = map[string]bootstrapTool{
+	ToolKind: {
+		latest:  `curl -sSLo "${PATH%%:*}/kind" https://storage.googleapis.com/bentheelder-kind-ci-builds/latest/kind-linux-amd64 && chmod +x "${PATH%%:*}/kind"`,
+		url:     "https://github.com/kubernetes-sigs/kind/releases/download/%s/kind-linux-amd64",
+		version: "kind version",
+	},
+	ToolK3d: {
+		latest:  "curl -s https://raw.githubusercontent.com/rancher/k3d/master/install.sh | bash",
+		url:     "https://github.com/k3d-io/k3d/releases/download/%s/k3d-linux-amd64",
+		version: "k3d version",
+	},
+	ToolKubectl: {
+		url:     "https://dl.k8s.io/release/%s/bin/linux/amd64/kubectl",
+		version: "kubectl version --client",
+	},
+	ToolMinikube: {
+		latest:  `curl -sSLo "${PATH%%:*}/minikube" https://storage.googleapis.com/minikube/releases/latest/minikube-linux-amd64 && chmod +x "${PATH%%:*}/minikube"`,
+		url:     "https://github.com/kubernetes/minikube/releases/download/%s/minikube-linux-amd64",
+		version: "minikube version",
+	},
+}
+
+// Tools lists the bootstrap tools the Cluster pod downloads or runs, sorted
+func (c Cluster) Tools() []string {
+	provisioner, _ := c.Provisioner()
+	if _, ok := provisioner.(PodBuilder); ok {
+		return nil
+	}
+
+	tools := []string{ToolKubectl}
+	if installer, ok := provisioner.(ToolInstaller); ok {
+		tools = append(tools, installer.Tools()...)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// UnpinnedTools lists the Cluster's bootstrap tools that get downloaded without being pinned to both
+// a version and a checksum. Tools the pod's image already has are only downloaded when given a version.
+func (c Cluster) UnpinnedTools() []string {
+	var unpinned []string
+	for _, tool := range c.Tools() {
+		pin := c.toolPin(tool)
+		if bootstrapTools[tool].latest == "" && (pin == nil || pin.Version == "") {
+			continue
+		}
+		if pin == nil || pin.Version == "" || pin.SHA256 == "" {
+			unpinned = append(unpinned, tool)
+		}
+	}
+	return unpinned
+}
+
+// toolPin resolves a tool's pin: spec.tools wins, then the KaasConfig's tools as snapshotted
+// in status.defaults, then the current KaasConfig's for Clusters that have no snapshot yet
+func (c Cluster) toolPin(name string) *ToolVersion {
+	if pin := c.Spec.Tools.get(name); pin != nil {
+		return pin
+	}
+	if c.Status.Defaults != nil {
+		return c.Status.Defaults.Tools.get(name)
+	}
+	if c.KaasConfig != nil {
+		return c.KaasConfig.Tools.get(name)
+	}
+	return nil
+}
+
+// RequiresPinnedTools returns whether the KaasConfig refuses Clusters with unpinned tools
+func (c Cluster) RequiresPinnedTools() bool {
+	return c.KaasConfig != nil && c.KaasConfig.RequirePinnedTools
+}
+
+// InstallTool returns the shell command that puts the tool on the pod's PATH. A pinned version is
+// downloaded from its release and checked against its SHA-256 checksum before it's made executable.
+func (c Cluster) InstallTool(name string) string {
+	tool := bootstrapTools[name]
+	pin := c.toolPin(name)
+	if pin == nil || pin.Version == "" {
+		return tool.latest
+	}
+
+	bin := fmt.Sprintf(`"${PATH%%%%:*}/%s"`, name)
+	command := fmt.Sprintf("curl -sSfLo %s %s", bin, fmt.Sprintf(tool.url, pin.Version))
+	if pin.SHA256 != "" {
+		command += fmt.Sprintf(" && echo \"%s  ${PATH%%%%:*}/%s\" | sha256sum -c -", pin.SHA256, name)
+	}
+	return command + " && chmod +x " + bin
+}
+
+// ToolVersions asks the Cluster pod which version of each bootstrap tool it ran
+func (c Cluster) ToolVersions(config *rest.Config) (map[string]string, error) {
+	tools := c.Tools()
+	if len(tools) == 0 {
+		return nil, nil
+	}
+
+	var commands []string
+	for _, tool := range tools {
+		commands = append(commands, fmt.Sprintf(`echo "%s=$(%s 2>/dev/null | grep -oE 'v[0-9]+\.[0-9]+\.[0-9]+[^ ,"]*' | head -1)"`, tool, bootstrapTools[tool].version))
+	}
+	data, err := c.execCommand(config, []string{"sh", "-c", strings.Join(commands, "; ")})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			versions[parts[0]] = parts[1]
+		}
+	}
+	return versions, nil
+}
+
+// setToolDefaults writes the pin each of the Cluster's tools resolves to into spec.tools
+func (c *Cluster) setToolDefaults() {
+	for _, tool := range c.Tools() {
+		pin := c.toolPin(tool)
+		if pin == nil || c.Spec.Tools.get(tool) != nil {
+			continue
+		}
+		if c.Spec.Tools == nil {
+			c.Spec.Tools = &BootstrapTools{}
+		}
+		pin = pin.DeepCopy()
+		switch tool {
+		case ToolKind:
+			c.Spec.Tools.Kind = pin
+		case ToolK3d:
+			c.Spec.Tools.K3d = pin
+		case ToolKubectl:
+			c.Spec.Tools.Kubectl = pin
+		case ToolMinikube:
+			c.Spec.Tools.Minikube = pin
+		}
+	}
+}
+
+// get returns the pin for a tool, or nil if there isn't one
+func (b *BootstrapTools) get(name string) *ToolVersion {
+	if b == nil {
+		return nil
+	}
+	switch name {
+	case ToolKind:
+		return b.Kind
+	case ToolK3d:
+		return b.K3d
+	case ToolKubectl:
+		return b.Kubectl
+	case ToolMinikube:
+		return b.Minikube
+	}
+	return nil
+}
+
+// validate checks that every pin names a release and a well-formed checksum
+func (b *BootstrapTools) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, tool := range []string{ToolK3d, ToolKind, ToolKubectl, ToolMinikube} {
+		pin := b.get(tool)
+		if pin == nil {
+			continue
+		}
+		toolPath := fldPath.Child(tool)
+		if !toolVersionPattern.MatchString(pin.Version) {
+			allErrs = append(allErrs, field.Invalid(toolPath.Child("version"), pin.Version, "must be a release such as v0.8.1"))
+		}
+		if pin.SHA256 != "" && !sha256Pattern.MatchString(pin.SHA256) {
+			allErrs = append(allErrs, field.Invalid(toolPath.Child("sha256"), pin.SHA256, "must be 64 lowercase hex characters"))
+		}
+	}
+	return allErrs
+}
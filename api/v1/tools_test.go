@@ -0,0 +1,175 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInstallTool(t *testing.T) {
+	checksum := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name  string
+		tool  string
+		tools *BootstrapTools
+		want  string
+	}{
+		{
+			name: "unpinned",
+			tool: ToolKind,
+			want: bootstrapTools[ToolKind].latest,
+		},
+		{
+			name: "unpinned tool the image comes with",
+			tool: ToolKubectl,
+		},
+		{
+			name:  "pin without a version",
+			tool:  ToolK3d,
+			tools: &BootstrapTools{K3d: &ToolVersion{SHA256: checksum}},
+			want:  bootstrapTools[ToolK3d].latest,
+		},
+		{
+			name:  "other tool pinned",
+			tool:  ToolKind,
+			tools: &BootstrapTools{Kubectl: &ToolVersion{Version: "v1.18.0"}},
+			want:  bootstrapTools[ToolKind].latest,
+		},
+		{
+			name:  "version",
+			tool:  ToolMinikube,
+			tools: &BootstrapTools{Minikube: &ToolVersion{Version: "v1.9.2"}},
+			want:  `curl -sSfLo "${PATH%%:*}/minikube" https://github.com/kubernetes/minikube/releases/download/v1.9.2/minikube-linux-amd64 && chmod +x "${PATH%%:*}/minikube"`,
+		},
+		{
+			name:  "version and checksum",
+			tool:  ToolKubectl,
+			tools: &BootstrapTools{Kubectl: &ToolVersion{Version: "v1.18.0", SHA256: checksum}},
+			want:  `curl -sSfLo "${PATH%%:*}/kubectl" https://dl.k8s.io/release/v1.18.0/bin/linux/amd64/kubectl && echo "` + checksum + `  ${PATH%%:*}/kubectl" | sha256sum -c - && chmod +x "${PATH%%:*}/kubectl"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.Tools = tt.tools
+
+			if got := c.InstallTool(tt.tool); got != tt.want {
+				t.Errorf("InstallTool(%s) = %q, want %q", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnpinnedTools(t *testing.T) {
+	checksum := strings.Repeat("a", 64)
+	pinned := &ToolVersion{Version: "v1.0.0", SHA256: checksum}
+
+	tests := []struct {
+		name        string
+		clusterType ClusterType
+		tools       *BootstrapTools
+		config      *KaasConfig
+		defaults    *ClusterDefaults
+		want        []string
+	}{
+		{
+			name:        "kind",
+			clusterType: KindCluster,
+			want:        []string{ToolKind},
+		},
+		{
+			name:        "k3s",
+			clusterType: K3sCluster,
+			want:        []string{ToolK3d},
+		},
+		{
+			name:        "minikube",
+			clusterType: MinikubeCluster,
+			want:        []string{ToolMinikube},
+		},
+		{
+			name:        "own pod",
+			clusterType: KwokCluster,
+		},
+		{
+			name:        "all pinned",
+			clusterType: KindCluster,
+			tools:       &BootstrapTools{Kind: pinned, Kubectl: pinned},
+		},
+		{
+			name:        "version without a checksum",
+			clusterType: KindCluster,
+			tools:       &BootstrapTools{Kind: &ToolVersion{Version: "v0.8.1"}, Kubectl: pinned},
+			want:        []string{ToolKind},
+		},
+		{
+			name:        "downloaded kubectl without a checksum",
+			clusterType: KindCluster,
+			tools:       &BootstrapTools{Kind: pinned, Kubectl: &ToolVersion{Version: "v1.18.0"}},
+			want:        []string{ToolKubectl},
+		},
+		{
+			name:        "pins for other tools",
+			clusterType: KindCluster,
+			tools:       &BootstrapTools{K3d: pinned, Minikube: pinned, Kubectl: pinned},
+			want:        []string{ToolKind},
+		},
+		{
+			name:        "KaasConfig pins",
+			clusterType: KindCluster,
+			config:      &KaasConfig{Tools: &BootstrapTools{Kind: pinned}},
+		},
+		{
+			name:        "spec pins win over the KaasConfig",
+			clusterType: KindCluster,
+			tools:       &BootstrapTools{Kind: &ToolVersion{Version: "v0.8.1"}},
+			config:      &KaasConfig{Tools: &BootstrapTools{Kind: pinned}},
+			want:        []string{ToolKind},
+		},
+		{
+			name:        "snapshotted KaasConfig pins win over the current KaasConfig",
+			clusterType: KindCluster,
+			config:      &KaasConfig{Tools: &BootstrapTools{Kind: pinned}},
+			defaults:    &ClusterDefaults{},
+			want:        []string{ToolKind},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cluster{}
+			c.Spec.ClusterType = tt.clusterType
+			c.Spec.Tools = tt.tools
+			c.KaasConfig = tt.config
+			c.Status.Defaults = tt.defaults
+
+			if got := c.UnpinnedTools(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnpinnedTools() = %v, want %v", got, tt.want)
+			}
+
+			// Whatever UnpinnedTools resolves is what gets written into the spec
+			c.setToolDefaults()
+			if got := c.UnpinnedTools(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnpinnedTools() after setToolDefaults() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,16 +21,53 @@ limitations under the License.
 package v1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapTools) DeepCopyInto(out *BootstrapTools) {
+	*out = *in
+	if in.Kind != nil {
+		in, out := &in.Kind, &out.Kind
+		*out = new(ToolVersion)
+		**out = **in
+	}
+	if in.K3d != nil {
+		in, out := &in.K3d, &out.K3d
+		*out = new(ToolVersion)
+		**out = **in
+	}
+	if in.Kubectl != nil {
+		in, out := &in.Kubectl, &out.Kubectl
+		*out = new(ToolVersion)
+		**out = **in
+	}
+	if in.Minikube != nil {
+		in, out := &in.Minikube, &out.Minikube
+		*out = new(ToolVersion)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapTools.
+func (in *BootstrapTools) DeepCopy() *BootstrapTools {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapTools)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	if in.KaasConfig != nil {
 		in, out := &in.KaasConfig, &out.KaasConfig
 		*out = new(KaasConfig)
@@ -56,6 +93,47 @@ func (in *Cluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCondition.
+func (in *ClusterCondition) DeepCopy() *ClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDefaults) DeepCopyInto(out *ClusterDefaults) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(corev1.ServicePort)
+		**out = **in
+	}
+	if in.Tools != nil {
+		in, out := &in.Tools, &out.Tools
+		*out = new(BootstrapTools)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDefaults.
+func (in *ClusterDefaults) DeepCopy() *ClusterDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterList) DeepCopyInto(out *ClusterList) {
 	*out = *in
@@ -106,6 +184,40 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Tools != nil {
+		in, out := &in.Tools, &out.Tools
+		*out = new(BootstrapTools)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Kind != nil {
+		in, out := &in.Kind, &out.Kind
+		*out = new(KindSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.K3s != nil {
+		in, out := &in.K3s, &out.K3s
+		*out = new(K3sConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSpec.
@@ -121,6 +233,46 @@ func (in *ClusterSpec) DeepCopy() *ClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ProvisioningStartedAt != nil {
+		in, out := &in.ProvisioningStartedAt, &out.ProvisioningStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ReadyAt != nil {
+		in, out := &in.ReadyAt, &out.ReadyAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LogExportStartedAt != nil {
+		in, out := &in.LogExportStartedAt, &out.LogExportStartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ToolVersions != nil {
+		in, out := &in.ToolVersions, &out.ToolVersions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.OverriddenFields != nil {
+		in, out := &in.OverriddenFields, &out.OverriddenFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Defaults != nil {
+		in, out := &in.Defaults, &out.Defaults
+		*out = new(ClusterDefaults)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
@@ -133,12 +285,220 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K3sConfig) DeepCopyInto(out *K3sConfig) {
+	*out = *in
+	if in.ServerArgs != nil {
+		in, out := &in.ServerArgs, &out.ServerArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AgentArgs != nil {
+		in, out := &in.AgentArgs, &out.AgentArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Disable != nil {
+		in, out := &in.Disable, &out.Disable
+		*out = make([]K3sComponent, len(*in))
+		copy(*out, *in)
+	}
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = new(K3sRegistries)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]K3sVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K3sConfig.
+func (in *K3sConfig) DeepCopy() *K3sConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(K3sConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K3sRegistries) DeepCopyInto(out *K3sRegistries) {
+	*out = *in
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make(map[string]K3sRegistryMirror, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Configs != nil {
+		in, out := &in.Configs, &out.Configs
+		*out = make(map[string]K3sRegistryConfig, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K3sRegistries.
+func (in *K3sRegistries) DeepCopy() *K3sRegistries {
+	if in == nil {
+		return nil
+	}
+	out := new(K3sRegistries)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K3sRegistryConfig) DeepCopyInto(out *K3sRegistryConfig) {
+	*out = *in
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(K3sRegistryTLS)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K3sRegistryConfig.
+func (in *K3sRegistryConfig) DeepCopy() *K3sRegistryConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(K3sRegistryConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K3sRegistryMirror) DeepCopyInto(out *K3sRegistryMirror) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K3sRegistryMirror.
+func (in *K3sRegistryMirror) DeepCopy() *K3sRegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(K3sRegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K3sRegistryTLS) DeepCopyInto(out *K3sRegistryTLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K3sRegistryTLS.
+func (in *K3sRegistryTLS) DeepCopy() *K3sRegistryTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(K3sRegistryTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K3sVolume) DeepCopyInto(out *K3sVolume) {
+	*out = *in
+	if in.NodeFilters != nil {
+		in, out := &in.NodeFilters, &out.NodeFilters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K3sVolume.
+func (in *K3sVolume) DeepCopy() *K3sVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(K3sVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KaasConfig) DeepCopyInto(out *KaasConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.DefaultPort = in.DefaultPort
+	if in.DefaultImages != nil {
+		in, out := &in.DefaultImages, &out.DefaultImages
+		*out = make(map[ClusterType]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DefaultCPU != nil {
+		in, out := &in.DefaultCPU, &out.DefaultCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DefaultMemory != nil {
+		in, out := &in.DefaultMemory, &out.DefaultMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DefaultTTL != nil {
+		in, out := &in.DefaultTTL, &out.DefaultTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxTTL != nil {
+		in, out := &in.MaxTTL, &out.MaxTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TeardownTimeout != nil {
+		in, out := &in.TeardownTimeout, &out.TeardownTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ProvisioningTimeout != nil {
+		in, out := &in.ProvisioningTimeout, &out.ProvisioningTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RequeueInterval != nil {
+		in, out := &in.RequeueInterval, &out.RequeueInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxRequeueInterval != nil {
+		in, out := &in.MaxRequeueInterval, &out.MaxRequeueInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HealthCheckInterval != nil {
+		in, out := &in.HealthCheckInterval, &out.HealthCheckInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TeardownLogsTTL != nil {
+		in, out := &in.TeardownLogsTTL, &out.TeardownLogsTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Tools != nil {
+		in, out := &in.Tools, &out.Tools
+		*out = new(BootstrapTools)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KaasConfig.
@@ -190,3 +550,117 @@ func (in *KaasConfigList) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KindMount) DeepCopyInto(out *KindMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindMount.
+func (in *KindMount) DeepCopy() *KindMount {
+	if in == nil {
+		return nil
+	}
+	out := new(KindMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KindNetworking) DeepCopyInto(out *KindNetworking) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindNetworking.
+func (in *KindNetworking) DeepCopy() *KindNetworking {
+	if in == nil {
+		return nil
+	}
+	out := new(KindNetworking)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KindNode) DeepCopyInto(out *KindNode) {
+	*out = *in
+	if in.ExtraMounts != nil {
+		in, out := &in.ExtraMounts, &out.ExtraMounts
+		*out = make([]KindMount, len(*in))
+		copy(*out, *in)
+	}
+	if in.KubeadmConfigPatches != nil {
+		in, out := &in.KubeadmConfigPatches, &out.KubeadmConfigPatches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindNode.
+func (in *KindNode) DeepCopy() *KindNode {
+	if in == nil {
+		return nil
+	}
+	out := new(KindNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KindSpec) DeepCopyInto(out *KindSpec) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]KindNode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(KindNetworking)
+		**out = **in
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KubeadmConfigPatches != nil {
+		in, out := &in.KubeadmConfigPatches, &out.KubeadmConfigPatches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ContainerdConfigPatches != nil {
+		in, out := &in.ContainerdConfigPatches, &out.ContainerdConfigPatches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindSpec.
+func (in *KindSpec) DeepCopy() *KindSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KindSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ToolVersion) DeepCopyInto(out *ToolVersion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ToolVersion.
+func (in *ToolVersion) DeepCopy() *ToolVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(ToolVersion)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,14 +19,23 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	honkv1 "github.com/jeefy/kaas/api/v1"
 	v1 "k8s.io/api/core/v1"
@@ -36,21 +45,29 @@ import (
 // ClusterReconciler reconciles a Cluster object
 type ClusterReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is how many Clusters can be reconciled at once, 1 if unset
+	MaxConcurrentReconciles int
 }
 
 // +kubebuilder:rbac:groups=honk.honk.ci,resources=clusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=honk.honk.ci,resources=clusters/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=honk.honk.ci,resources=kaasconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods;services;configmaps;secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
-func (r *ClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *ClusterReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("cluster", req.NamespacedName)
-	var err error
-	var update bool
+	var update []string
 
 	var kaasConfig honkv1.KaasConfig
-	if err = r.Get(ctx, types.NamespacedName{Name: "config", Namespace: "kaas-system"}, &kaasConfig); err != nil {
+	if err = r.Get(ctx, types.NamespacedName{Name: honkv1.KaasConfigName, Namespace: honkv1.KaasConfigNamespace}, &kaasConfig); err != nil {
 		// We proceed as normal. The config just overrides the defaults.
 		log.Info(err.Error())
 	}
@@ -67,6 +84,81 @@ func (r *ClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	cluster = cluster.SetConfig(&kaasConfig)
 
+	// Keep our finalizers in line with the spec. New finalizers can't be added once deletion has started.
+	finalizers := len(cluster.GetFinalizers())
+	if cluster.DeletionTimestamp.IsZero() {
+		if !hasFinalizer(&cluster, honkv1.TeardownFinalizer) {
+			controllerutil.AddFinalizer(&cluster, honkv1.TeardownFinalizer)
+		}
+		if cluster.Spec.DeletionProtection && !hasFinalizer(&cluster, honkv1.DeletionProtectionFinalizer) {
+			controllerutil.AddFinalizer(&cluster, honkv1.DeletionProtectionFinalizer)
+		}
+	}
+	if !cluster.Spec.DeletionProtection && hasFinalizer(&cluster, honkv1.DeletionProtectionFinalizer) {
+		controllerutil.RemoveFinalizer(&cluster, honkv1.DeletionProtectionFinalizer)
+	}
+	if len(cluster.GetFinalizers()) != finalizers {
+		if err = r.Update(context.TODO(), &cluster); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Whatever happens below, persist any status changes on the way out.
+	// Only the status subresource is written so spec edits are never clobbered.
+	original := cluster.DeepCopy()
+	defer func() {
+		cluster.Status.ObservedGeneration = cluster.Generation
+		if reflect.DeepEqual(original.Status, cluster.Status) {
+			return
+		}
+		r.recordPhaseChange(&cluster, original.Status.Phase)
+		if statusErr := client.IgnoreNotFound(r.Status().Patch(context.TODO(), &cluster, client.MergeFrom(original))); statusErr != nil {
+			log.Info(fmt.Sprintf("Unable to update status: %s", statusErr.Error()))
+			if err == nil {
+				err = statusErr
+			}
+		}
+	}()
+
+	if !cluster.DeletionTimestamp.IsZero() {
+		if cluster.Spec.DeletionProtection {
+			cluster.SetCondition(honkv1.ClusterDeletionBlocked, v1.ConditionTrue, "DeletionProtectionEnabled", "Set spec.deletionProtection to false to let the Cluster be deleted")
+			return ctrl.Result{}, nil
+		}
+		return r.finalize(&cluster)
+	}
+
+	// Pin the KaasConfig defaults this Cluster was created under, so editing the KaasConfig
+	// later doesn't quietly recreate its pod
+	cluster.SnapshotDefaults()
+
+	cluster.Status.ExpiresAt = cluster.Expiry()
+	expired := cluster.Status.ExpiresAt != nil && !time.Now().Before(cluster.Status.ExpiresAt.Time)
+	if expired && cluster.Spec.DeletionProtection {
+		log.Info(fmt.Sprintf("Cluster expired at %s, but deletion protection is enabled", cluster.Status.ExpiresAt))
+		if !cluster.IsConditionTrue(honkv1.ClusterDeletionBlocked) {
+			r.Recorder.Event(&cluster, v1.EventTypeWarning, "DeletionBlocked", fmt.Sprintf("Cluster expired at %s but deletion protection is enabled", cluster.Status.ExpiresAt))
+		}
+		cluster.SetCondition(honkv1.ClusterDeletionBlocked, v1.ConditionTrue, "Expired", fmt.Sprintf("Cluster expired at %s, set spec.deletionProtection to false to let it be deleted", cluster.Status.ExpiresAt))
+	} else if expired {
+		log.Info(fmt.Sprintf("Cluster expired at %s, deleting", cluster.Status.ExpiresAt))
+		r.Recorder.Event(&cluster, v1.EventTypeNormal, "Expired", fmt.Sprintf("Cluster expired at %s, deleting", cluster.Status.ExpiresAt))
+		err = r.Delete(context.TODO(), &cluster)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	} else if cluster.GetCondition(honkv1.ClusterDeletionBlocked) != nil {
+		cluster.SetCondition(honkv1.ClusterDeletionBlocked, v1.ConditionFalse, "NotBlocked", "")
+	}
+
+	if cluster.Status.ExpiresAt != nil && !expired {
+		// Make sure we come back around in time to clean up, whatever else happens
+		expiresIn := time.Until(cluster.Status.ExpiresAt.Time)
+		defer func() {
+			if result.RequeueAfter == 0 || result.RequeueAfter > expiresIn {
+				result.RequeueAfter = expiresIn
+			}
+		}()
+	}
+
 	cm := cluster.ConfigMap(req.Namespace)
 	foundCM := &v1.ConfigMap{}
 	err = r.Get(context.TODO(), types.NamespacedName{Name: cm.GetName(), Namespace: cm.GetNamespace()}, foundCM)
@@ -74,8 +166,10 @@ func (r *ClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		log.Info(fmt.Sprintf("Creating ConfigMap %s/%s\n", cm.GetNamespace(), cm.GetName()))
 		err = r.Create(context.TODO(), cm)
 		if err != nil {
+			r.Recorder.Event(&cluster, v1.EventTypeWarning, "CreateFailed", fmt.Sprintf("Failed to create ConfigMap %s: %s", cm.GetName(), err.Error()))
 			return ctrl.Result{}, err
 		}
+		r.Recorder.Event(&cluster, v1.EventTypeNormal, "Created", fmt.Sprintf("Created ConfigMap %s", cm.GetName()))
 	} else if err != nil && errors.IsAlreadyExists(err) {
 		return ctrl.Result{}, nil
 	} else if err != nil {
@@ -88,10 +182,17 @@ func (r *ClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			if err != nil {
 				return ctrl.Result{}, err
 			}
-			update = true
+			r.Recorder.Event(&cluster, v1.EventTypeNormal, "Updated", fmt.Sprintf("Updated ConfigMap %s", cm.GetName()))
+			update = append(update, "config files")
 		}
 	}
 
+	overridden := cluster.OverriddenFields()
+	if len(overridden) > 0 && !reflect.DeepEqual(overridden, cluster.Status.OverriddenFields) {
+		r.Recorder.Event(&cluster, v1.EventTypeWarning, "FieldsOverridden", fmt.Sprintf("The controller replaces %s with its own values", strings.Join(overridden, ", ")))
+	}
+	cluster.Status.OverriddenFields = overridden
+
 	svc, err := cluster.Service()
 	if err != nil {
 		return ctrl.Result{}, err
@@ -102,106 +203,845 @@ func (r *ClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		log.Info(fmt.Sprintf("Creating Service %s/%s\n", cm.GetNamespace(), cm.GetName()))
 		err = r.Create(context.TODO(), svc)
 		if err != nil {
+			r.Recorder.Event(&cluster, v1.EventTypeWarning, "CreateFailed", fmt.Sprintf("Failed to create Service %s: %s", svc.GetName(), err.Error()))
 			return ctrl.Result{}, err
 		}
+		r.Recorder.Event(&cluster, v1.EventTypeNormal, "Created", fmt.Sprintf("Created %s Service %s", svc.Spec.Type, svc.GetName()))
 	} else if err != nil && errors.IsAlreadyExists(err) {
 		return ctrl.Result{}, nil
 	} else if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if err = r.ensureBootstrapSecret(&cluster); err != nil {
+		r.Recorder.Event(&cluster, v1.EventTypeWarning, "CreateFailed", fmt.Sprintf("Failed to create Secret %s: %s", cluster.BootstrapSecretName(), err.Error()))
+		return ctrl.Result{}, err
+	}
+
 	pod := cluster.Pod(req.Namespace)
 	foundPod := &v1.Pod{}
 	err = r.Get(context.TODO(), types.NamespacedName{Name: pod.GetName(), Namespace: pod.GetNamespace()}, foundPod)
 
 	if err != nil && errors.IsNotFound(err) {
+		if unpinned := cluster.UnpinnedTools(); cluster.RequiresPinnedTools() && len(unpinned) > 0 {
+			// The webhook refuses these, but Clusters created before the policy was enabled still get here
+			message := fmt.Sprintf("The KaasConfig requires a version and sha256 in spec.tools for: %s", strings.Join(unpinned, ", "))
+			if cluster.Status.Phase != honkv1.ClusterPhaseFailed {
+				r.Recorder.Event(&cluster, v1.EventTypeWarning, "UnpinnedTools", message)
+			}
+			setNotReady(&cluster, honkv1.ClusterPhaseFailed, "UnpinnedTools", message)
+			return ctrl.Result{}, nil
+		}
+
 		log.Info(fmt.Sprintf("Creating Pod %s/%s\n", pod.GetNamespace(), pod.GetName()))
 		err = r.Create(context.TODO(), pod)
 		if err != nil && !errors.IsAlreadyExists(err) {
+			r.Recorder.Event(&cluster, v1.EventTypeWarning, "CreateFailed", fmt.Sprintf("Failed to create Pod %s: %s", pod.GetName(), err.Error()))
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
+		r.Recorder.Event(&cluster, v1.EventTypeNormal, "Created", fmt.Sprintf("Created Pod %s", pod.GetName()))
+		setNotReady(&cluster, honkv1.ClusterPhasePending, "PodCreated", "Waiting for the Cluster pod to be scheduled")
+		resetProvisioning(&cluster, "PodCreated")
+		return ctrl.Result{RequeueAfter: cluster.RequeueBackoff(0)}, nil
 	} else if err != nil {
 		return ctrl.Result{}, err
 	} else {
-		if !cluster.PodSpecEquals(foundPod) {
-			update = true
-		}
+		update = append(update, cluster.PodSpecDiff(foundPod)...)
 
-		if update {
+		if len(update) > 0 {
 			// Refresh pods
+			for _, field := range update {
+				clusterPodRecreations.WithLabelValues(string(cluster.Spec.ClusterType), field).Inc()
+			}
+			r.Recorder.Event(&cluster, v1.EventTypeNormal, "Recreating", fmt.Sprintf("Recreating Pod %s because its %s changed", foundPod.GetName(), strings.Join(update, ", ")))
+			setNotReady(&cluster, honkv1.ClusterPhasePending, "PodRecreating", "The Cluster spec changed and the pod is being recreated")
+			resetProvisioning(&cluster, "PodRecreating")
+			// A new spec deserves a fresh set of retries
+			cluster.Status.Attempts = 0
+			cluster.Status.LastError = ""
 			err = r.Delete(context.TODO(), foundPod)
 			if err != nil && errors.IsNotFound(err) {
-				return ctrl.Result{}, nil
+				return ctrl.Result{RequeueAfter: cluster.RequeueBackoff(0)}, nil
+			}
+
+			return ctrl.Result{RequeueAfter: cluster.RequeueBackoff(0)}, err
+		}
+
+		if err = r.reconcileAgents(&cluster, foundPod); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		setPodScheduled(&cluster, foundPod)
+		provisioningStartedAt := foundPod.CreationTimestamp
+		cluster.Status.ProvisioningStartedAt = &provisioningStartedAt
+
+		if foundPod.Spec.RestartPolicy != v1.RestartPolicyNever {
+			// Pods created before restartPolicy Never crash-loop instead of failing
+			if restarts, message := containerRestarts(foundPod); restarts > 0 {
+				return r.handleFailure(&cluster, foundPod, "ContainerRestarted", fmt.Sprintf("Container restarted %d times: %s", restarts, message))
+			}
+		} else if name, message := terminatedContainer(foundPod); name != "" && foundPod.Status.Phase == v1.PodRunning {
+			// A pod with several containers keeps running when one of them dies
+			return r.handleFailure(&cluster, foundPod, "ContainerTerminated", fmt.Sprintf("Container %s exited: %s", name, message))
+		}
+
+		switch foundPod.Status.Phase {
+		case v1.PodFailed:
+			return r.handleFailure(&cluster, foundPod, "PodFailed", podMessage(foundPod))
+		case v1.PodRunning:
+		default:
+			setNotReady(&cluster, honkv1.ClusterPhasePending, "PodPending", podMessage(foundPod))
+			return r.requeueWhileProvisioning(&cluster, foundPod)
+		}
+
+		// Once the Cluster has been ready, losing readiness means the nested cluster is
+		// unhealthy rather than still coming up, so the provisioning deadline no longer applies
+		if cluster.Status.ReadyAt != nil && !containerReady(foundPod, cluster.PrimaryContainer()) {
+			message := "The Cluster pod stopped passing its readiness check"
+			if cluster.IsConditionTrue(honkv1.ClusterHealthy) {
+				r.Recorder.Event(&cluster, v1.EventTypeWarning, "Unhealthy", message)
 			}
+			cluster.SetCondition(honkv1.ClusterHealthy, v1.ConditionFalse, "PodNotReady", message)
+			setNotReady(&cluster, honkv1.ClusterPhaseDegraded, "PodNotReady", message)
+			return ctrl.Result{RequeueAfter: cluster.HealthCheckInterval()}, nil
+		}
 
+		config, err := ctrl.GetConfig()
+		if err != nil {
+			log.Info("Can't get config from ctrl")
 			return ctrl.Result{}, err
 		}
-		if foundPod.Status.Phase == v1.PodRunning {
-			if foundPod.Status.ContainerStatuses[0].Ready {
-				config, err := ctrl.GetConfig()
+
+		if !containerReady(foundPod, cluster.PrimaryContainer()) {
+			bootstrapped, manifestsApplied, err := cluster.Progress(config)
+			observeExec("progress", err)
+			if err != nil {
+				log.Info(fmt.Sprintf("Can't check bootstrap progress: %s", err.Error()))
+				return r.requeueWhileProvisioning(&cluster, foundPod)
+			}
+			setProgress(&cluster, bootstrapped, manifestsApplied)
+			// The readiness probe waits for the nodes the controller creates
+			if bootstrapped {
+				r.syncNodes(&cluster, config)
+			}
+			return r.requeueWhileProvisioning(&cluster, foundPod)
+		}
+
+		cluster.Status.Phase = honkv1.ClusterPhaseFetchingKubeconfig
+		cluster.SetCondition(honkv1.ClusterBootstrapped, v1.ConditionTrue, "Bootstrapped", "")
+		cluster.SetCondition(honkv1.ClusterManifestsApplied, v1.ConditionTrue, "ManifestsApplied", "")
+		if foundSvc.Spec.Type == v1.ServiceTypeLoadBalancer && len(foundSvc.Status.LoadBalancer.Ingress) == 0 {
+			setNotReady(&cluster, honkv1.ClusterPhaseFetchingKubeconfig, "WaitingForLoadBalancer", "Waiting for the Service to be assigned a LoadBalancer IP")
+			cluster.SetCondition(honkv1.ClusterKubeconfigReady, v1.ConditionFalse, "WaitingForLoadBalancer", "")
+			return r.requeueWhileProvisioning(&cluster, foundPod)
+		}
+
+		// Kubeconfigs only change along with the pod, which resets ReadyAt, so once the
+		// Cluster has been ready they only need fetching again if their Secret went missing
+		if cluster.Status.ReadyAt == nil || r.kubeconfigStored(&cluster) != nil {
+			files := cluster.KubeconfigPaths()
+
+			fetchStart := time.Now()
+			endpoint, err := cluster.APIEndpoint(config, foundSvc)
+			if err != nil {
+				kubeconfigFetchErrors.WithLabelValues(string(cluster.Spec.ClusterType)).Inc()
+				log.Info("Can't work out the API endpoint")
+				cluster.SetCondition(honkv1.ClusterKubeconfigReady, v1.ConditionFalse, "EndpointUnknown", err.Error())
+				return ctrl.Result{}, err
+			}
+			cluster.Status.APIEndpoint = endpoint
+
+			if err = r.ensureServingCertificate(&cluster, foundSvc, endpoint); err != nil {
+				r.Recorder.Event(&cluster, v1.EventTypeWarning, "UpdateFailed", fmt.Sprintf("Failed to re-issue the API server certificate: %s", err.Error()))
+				return ctrl.Result{}, err
+			}
+
+			kubeconfigs, err := cluster.Kubeconfig(config, endpoint, files)
+			kubeconfigFetchTime.WithLabelValues(string(cluster.Spec.ClusterType)).Observe(time.Since(fetchStart).Seconds())
+			observeExec("kubeconfig", err)
+			log.Info(fmt.Sprintf("Gathered %d Kubeconfigs", len(kubeconfigs)))
+			if err != nil {
+				kubeconfigFetchErrors.WithLabelValues(string(cluster.Spec.ClusterType)).Inc()
+				log.Info("Can't get adminkubeconfig from cluster")
+				r.Recorder.Event(&cluster, v1.EventTypeWarning, "KubeconfigFailed", fmt.Sprintf("Failed to fetch kubeconfigs: %s", err.Error()))
+				cluster.SetCondition(honkv1.ClusterKubeconfigReady, v1.ConditionFalse, "KubeconfigFetchFailed", err.Error())
+				return ctrl.Result{}, err
+			}
+			if len(kubeconfigs) > 0 {
+				secret, err := cluster.Secret("kubeconfig", kubeconfigs)
 				if err != nil {
-					log.Info("Can't get config from ctrl")
+					log.Info("Can't generate kubeconfig secrets")
 					return ctrl.Result{}, err
 				}
-				if foundSvc != nil {
-					files := make(map[string]string)
-					files["root-config"] = "/root/.kube/config"
-					files["default-config"] = "/tmp/kube/k8s-kind-user-default-conf"
-
-					kubeconfigs, err := cluster.Kubeconfig(config, foundSvc, files)
-					log.Info(fmt.Sprintf("Gathered %d Kubeconfigs", len(kubeconfigs)))
+				cluster.Status.KubeconfigSecret = secret.GetName()
+				foundSecret := v1.Secret{}
+				createSecret := false
+				err = r.Get(context.TODO(), types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()}, &foundSecret)
+				if err == nil && !secretDataEquals(&foundSecret, secret.StringData) {
+					err = r.Delete(context.TODO(), &foundSecret)
+					createSecret = true
 					if err != nil {
-						log.Info("Can't get adminkubeconfig from cluster")
+						log.Info("Can't delete old secrets")
 						return ctrl.Result{}, err
 					}
-					if len(kubeconfigs) > 0 {
-						secret, err := cluster.Secret("kubeconfig", kubeconfigs)
-						if err != nil {
-							log.Info("Can't generate kubeconfig secrets")
-							return ctrl.Result{}, err
-						}
-						foundSecret := v1.Secret{}
-						createSecret := false
-						err = r.Get(context.TODO(), types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()}, &foundSecret)
-						if err == nil && !reflect.DeepEqual(foundSecret.StringData, secret.StringData) {
-							err = r.Delete(context.TODO(), &foundSecret)
-							createSecret = true
-							if err != nil {
-								log.Info("Can't delete old secrets")
-								return ctrl.Result{}, err
-							}
-						}
-						if err != nil && errors.IsNotFound(err) {
-							createSecret = true
-						}
-						if createSecret {
-							err = r.Create(context.TODO(), secret)
-							if err != nil {
-								return ctrl.Result{}, err
-							}
-						}
-					}
 				}
-				if !cluster.Status.Ready {
-					cluster.Status.Ready = true
-
-					if len(svc.Status.LoadBalancer.Ingress) > 0 {
-						cluster.Status.LoadBalancerIP = svc.Status.LoadBalancer.Ingress[0].IP
-					}
-
-					err = r.Update(context.TODO(), &cluster)
+				if err != nil && errors.IsNotFound(err) {
+					createSecret = true
+				}
+				if createSecret {
+					err = r.Create(context.TODO(), secret)
 					if err != nil {
+						cluster.SetCondition(honkv1.ClusterKubeconfigReady, v1.ConditionFalse, "SecretCreateFailed", err.Error())
+						r.Recorder.Event(&cluster, v1.EventTypeWarning, "KubeconfigFailed", fmt.Sprintf("Failed to store kubeconfigs in Secret %s: %s", secret.GetName(), err.Error()))
 						return ctrl.Result{}, err
 					}
+					r.Recorder.Event(&cluster, v1.EventTypeNormal, "KubeconfigUpdated", fmt.Sprintf("Stored %d kubeconfigs in Secret %s", len(kubeconfigs), secret.GetName()))
 				}
 			}
+
+			toolVersions, err := cluster.ToolVersions(config)
+			observeExec("tool-versions", err)
+			if err != nil {
+				log.Info(fmt.Sprintf("Can't read bootstrap tool versions: %s", err.Error()))
+			}
+			cluster.Status.ToolVersions = toolVersions
 		}
+		cluster.SetCondition(honkv1.ClusterKubeconfigReady, v1.ConditionTrue, "KubeconfigStored", "")
+
+		if healthy, message := r.checkHealth(&cluster, config); !healthy {
+			if cluster.IsConditionTrue(honkv1.ClusterHealthy) {
+				r.Recorder.Event(&cluster, v1.EventTypeWarning, "Unhealthy", message)
+			}
+			cluster.SetCondition(honkv1.ClusterHealthy, v1.ConditionFalse, "HealthCheckFailed", message)
+			setNotReady(&cluster, honkv1.ClusterPhaseDegraded, "Unhealthy", message)
+			return ctrl.Result{RequeueAfter: cluster.HealthCheckInterval()}, nil
+		} else {
+			cluster.SetCondition(honkv1.ClusterHealthy, v1.ConditionTrue, "HealthCheckPassed", message)
+		}
+
+		if cluster.Status.ReadyAt == nil {
+			now := metav1.Now()
+			cluster.Status.ReadyAt = &now
+			clusterTimeToReady.WithLabelValues(string(cluster.Spec.ClusterType)).Observe(time.Since(foundPod.CreationTimestamp.Time).Seconds())
+		}
+		cluster.Status.Ready = true
+		cluster.Status.Phase = honkv1.ClusterPhaseReady
+		cluster.SetCondition(honkv1.ClusterReady, v1.ConditionTrue, "ClusterReady", "")
+
+		if len(foundSvc.Status.LoadBalancer.Ingress) > 0 {
+			cluster.Status.LoadBalancerIP = foundSvc.Status.LoadBalancer.Ingress[0].IP
+		}
+
+		if workers, ok := cluster.ManagedNodes(); ok && cluster.Status.NodeCount != workers {
+			r.syncNodes(&cluster, config)
+		}
+	}
+
+	// Keep checking on the nested cluster's health
+	return ctrl.Result{RequeueAfter: cluster.HealthCheckInterval()}, nil
+}
+
+// ensureBootstrapSecret generates the credentials the Cluster's provisioner needs, once
+func (r *ClusterReconciler) ensureBootstrapSecret(cluster *honkv1.Cluster) error {
+	provisioner, _ := cluster.Provisioner()
+	builder, ok := provisioner.(honkv1.SecretBuilder)
+	if !ok {
+		return nil
+	}
+
+	foundSecret := v1.Secret{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: cluster.BootstrapSecretName(), Namespace: cluster.Namespace}, &foundSecret)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	data, err := builder.BootstrapSecretData(*cluster)
+	if err != nil {
+		return err
+	}
+	secret := cluster.BootstrapSecret(data)
+	if err = r.Create(context.TODO(), secret); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	r.Recorder.Event(cluster, v1.EventTypeNormal, "Created", fmt.Sprintf("Created Secret %s", secret.GetName()))
+	return nil
+}
+
+// ensureServingCertificate re-issues the nested API server's certificate in the bootstrap Secret
+// when it doesn't cover the Service's ClusterIP or the endpoint clients are given. Neither is
+// known when the Secret is first generated. The Secret volume and the API server both pick up
+// the new certificate without restarting the pod.
+func (r *ClusterReconciler) ensureServingCertificate(cluster *honkv1.Cluster, svc *v1.Service, endpoint string) error {
+	provisioner, _ := cluster.Provisioner()
+	if _, ok := provisioner.(honkv1.SecretBuilder); !ok {
+		return nil
+	}
+
+	secret := &v1.Secret{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: cluster.BootstrapSecretName(), Namespace: cluster.Namespace}, secret); err != nil {
+		return err
+	}
+
+	var hosts []string
+	if svc.Spec.ClusterIP != v1.ClusterIPNone {
+		hosts = append(hosts, svc.Spec.ClusterIP)
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		hosts = append(hosts, host)
+	}
+	reissued, err := cluster.EnsureServingCertificate(secret.Data, hosts...)
+	if err != nil || !reissued {
+		return err
+	}
+
+	if err = r.Update(context.TODO(), secret); err != nil {
+		return err
+	}
+	r.Recorder.Event(cluster, v1.EventTypeNormal, "CertificateReissued", fmt.Sprintf("Re-issued the API server certificate for %s", strings.Join(hosts, ", ")))
+	return nil
+}
+
+// reconcileAgents scales the Cluster's agent pods to spec.workers. Agents that belong to an
+// earlier Cluster pod can't rejoin a fresh nested cluster, so they're replaced along with it.
+func (r *ClusterReconciler) reconcileAgents(cluster *honkv1.Cluster, pod *v1.Pod) error {
+	desired := make(map[string]*v1.Pod)
+	for _, agent := range cluster.AgentPods(cluster.Namespace) {
+		desired[agent.Name] = agent
+	}
+
+	var foundAgents v1.PodList
+	if err := r.List(context.TODO(), &foundAgents, client.InNamespace(cluster.Namespace), client.MatchingLabels(cluster.AgentSelector())); err != nil {
+		return err
+	}
+	for i := range foundAgents.Items {
+		foundAgent := &foundAgents.Items[i]
+		_, wanted := desired[foundAgent.Name]
+		delete(desired, foundAgent.Name)
+		if !foundAgent.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		reason := ""
+		if !wanted {
+			reason = "spec.workers was lowered"
+		} else if foundAgent.Annotations[serverPodAnnotation] != string(pod.UID) {
+			reason = "the Cluster pod was recreated"
+		} else if diff := cluster.AgentPodSpecDiff(foundAgent); len(diff) > 0 {
+			reason = fmt.Sprintf("its %s changed", strings.Join(diff, ", "))
+		} else {
+			continue
+		}
+
+		if err := r.Delete(context.TODO(), foundAgent); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		r.Recorder.Event(cluster, v1.EventTypeNormal, "Deleted", fmt.Sprintf("Deleted agent Pod %s because %s", foundAgent.GetName(), reason))
+	}
+
+	// Replaced agents are created once the old ones are gone
+	for _, agent := range desired {
+		agent.Annotations = map[string]string{
+			serverPodAnnotation: string(pod.UID),
+		}
+		if err := r.Create(context.TODO(), agent); err != nil {
+			if errors.IsAlreadyExists(err) {
+				continue
+			}
+			r.Recorder.Event(cluster, v1.EventTypeWarning, "CreateFailed", fmt.Sprintf("Failed to create agent Pod %s: %s", agent.GetName(), err.Error()))
+			return err
+		}
+		r.Recorder.Event(cluster, v1.EventTypeNormal, "Created", fmt.Sprintf("Created agent Pod %s", agent.GetName()))
+	}
+	return nil
+}
+
+// syncNodes creates and deletes the nested cluster's nodes to match spec.workers, for the
+// cluster types whose nodes are plain objects
+func (r *ClusterReconciler) syncNodes(cluster *honkv1.Cluster, config *rest.Config) {
+	log := r.Log.WithValues("cluster", types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+
+	workers, ok := cluster.ManagedNodes()
+	if !ok {
+		return
 	}
 
-	return ctrl.Result{}, nil
+	err := cluster.SyncNodes(config)
+	observeExec("nodes", err)
+	if err != nil {
+		log.Info(fmt.Sprintf("Can't sync nodes: %s", err.Error()))
+		r.Recorder.Event(cluster, v1.EventTypeWarning, "NodeSyncFailed", fmt.Sprintf("Failed to scale the nested cluster to %d nodes: %s", workers, err.Error()))
+		return
+	}
+	if cluster.Status.ReadyAt != nil {
+		r.Recorder.Event(cluster, v1.EventTypeNormal, "NodesSynced", fmt.Sprintf("Scaled the nested cluster from %d to %d nodes", cluster.Status.NodeCount, workers))
+	}
+}
+
+// kubeconfigStored checks that the Cluster's kubeconfig Secret still holds the admin kubeconfig
+func (r *ClusterReconciler) kubeconfigStored(cluster *honkv1.Cluster) error {
+	if cluster.Status.KubeconfigSecret == "" {
+		return fmt.Errorf("no kubeconfig Secret recorded yet")
+	}
+
+	secret := v1.Secret{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: cluster.Status.KubeconfigSecret, Namespace: cluster.Namespace}, &secret)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secret.Data["root-config"]; !ok {
+		return fmt.Errorf("Secret %s has no root-config", secret.Name)
+	}
+	return nil
+}
+
+// checkHealth checks on the nested cluster from inside the Cluster pod, refreshing what's
+// recorded about it along the way. It returns why the cluster is unhealthy, if it is, or
+// which kube-system pods are failing in a cluster that is otherwise healthy.
+func (r *ClusterReconciler) checkHealth(cluster *honkv1.Cluster, config *rest.Config) (bool, string) {
+	version, nodes, pods, err := cluster.NestedStatus(config)
+	observeExec("health", err)
+	if err != nil {
+		return false, fmt.Sprintf("The nested API server isn't ready: %s", err.Error())
+	}
+	cluster.Status.KubernetesVersion = version
+	cluster.Status.NodeCount = int32(len(nodes.Items))
+
+	notReadyNodes, failingPods := nestedHealth(nodes, pods)
+	if notReadyNodes > 0 {
+		return false, fmt.Sprintf("%d of %d nodes NotReady", notReadyNodes, len(nodes.Items))
+	}
+	// A single misbehaving add-on doesn't make the whole cluster unusable
+	if len(failingPods) > 0 {
+		return true, fmt.Sprintf("kube-system pods failing: %s", strings.Join(failingPods, ", "))
+	}
+	return true, ""
 }
 
+// finalize tears down the nested cluster of a deleted Cluster and then releases it
+func (r *ClusterReconciler) finalize(cluster *honkv1.Cluster) (ctrl.Result, error) {
+	log := r.Log.WithValues("cluster", types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+
+	if !hasFinalizer(cluster, honkv1.TeardownFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// Report that we're terminating before doing anything slow
+	if cluster.Status.Phase != honkv1.ClusterPhaseTerminating {
+		setNotReady(cluster, honkv1.ClusterPhaseTerminating, "Terminating", "Tearing down the nested cluster")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	foundPod := &v1.Pod{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}, foundPod)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	// Nothing to tear down unless the nested cluster is still running
+	if err == nil && foundPod.DeletionTimestamp.IsZero() && foundPod.Status.Phase == v1.PodRunning && cluster.HasTeardown() {
+		config, err := ctrl.GetConfig()
+		if err != nil {
+			log.Info("Can't get config from ctrl")
+			return ctrl.Result{}, err
+		}
+
+		if result, done := r.teardown(cluster, config); !done {
+			return result, nil
+		}
+	}
+
+	controllerutil.RemoveFinalizer(cluster, honkv1.TeardownFinalizer)
+	return ctrl.Result{}, r.Update(context.TODO(), cluster)
+}
+
+// teardown starts the teardown in the background inside the Cluster pod and then polls it,
+// so a slow teardown never blocks other Clusters from being reconciled. It returns whether
+// the Cluster can be released.
+func (r *ClusterReconciler) teardown(cluster *honkv1.Cluster, config *rest.Config) (ctrl.Result, bool) {
+	log := r.Log.WithValues("cluster", types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+	poll := ctrl.Result{RequeueAfter: cluster.RequeueBackoff(0)}
+
+	// Don't hold the Cluster hostage, the pod is about to be garbage collected anyway
+	giveUp := func(err error) (ctrl.Result, bool) {
+		log.Info(fmt.Sprintf("Teardown failed, deleting anyway: %s", err.Error()))
+		r.Recorder.Event(cluster, v1.EventTypeWarning, "TeardownFailed", fmt.Sprintf("Teardown failed, deleting anyway: %s", err.Error()))
+		return ctrl.Result{}, true
+	}
+
+	// The pod kills the teardown after the timeout, this covers it never starting or reporting back
+	deadline := cluster.DeletionTimestamp.Add(cluster.TeardownTimeout() + teardownGracePeriod)
+	started, finished, err := cluster.TeardownProgress(config)
+	observeExec("teardown-progress", err)
+	if err != nil {
+		if time.Now().After(deadline) {
+			return giveUp(err)
+		}
+		log.Info(fmt.Sprintf("Can't check on the teardown: %s", err.Error()))
+		return poll, false
+	}
+
+	if !started {
+		err = cluster.StartTeardown(config)
+		observeExec("teardown-start", err)
+		if err != nil {
+			if time.Now().After(deadline) {
+				return giveUp(err)
+			}
+			log.Info(fmt.Sprintf("Can't start the teardown: %s", err.Error()))
+			return poll, false
+		}
+		log.Info(fmt.Sprintf("Tearing down %s cluster, waiting up to %s", cluster.Spec.ClusterType, cluster.TeardownTimeout()))
+		r.Recorder.Event(cluster, v1.EventTypeNormal, "TearingDown", fmt.Sprintf("Tearing down the %s cluster, waiting up to %s", cluster.Spec.ClusterType, cluster.TeardownTimeout()))
+		return poll, false
+	}
+
+	if !finished {
+		if time.Now().After(deadline) {
+			return giveUp(fmt.Errorf("teardown didn't finish within %s", cluster.TeardownTimeout()))
+		}
+		return poll, false
+	}
+
+	if cluster.KaasConfig != nil && cluster.KaasConfig.ExportLogsOnTeardown {
+		r.storeTeardownLogs(cluster, config)
+	}
+
+	output, err := cluster.TeardownResult(config)
+	observeExec("teardown", err)
+	log.Info(fmt.Sprintf("Teardown output: %s", output))
+	if err != nil {
+		return giveUp(err)
+	}
+	return ctrl.Result{}, true
+}
+
+// storeTeardownLogs saves the logs the teardown exported somewhere that outlives the Cluster
+func (r *ClusterReconciler) storeTeardownLogs(cluster *honkv1.Cluster, config *rest.Config) {
+	log := r.Log.WithValues("cluster", types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+
+	bundle, err := cluster.TeardownLogs(config)
+	observeExec("export-logs", err)
+	if err != nil {
+		log.Info(fmt.Sprintf("Can't export logs: %s", err.Error()))
+		return
+	}
+	if len(bundle) > maxLogBundleSize {
+		log.Info(fmt.Sprintf("Exported logs are %d bytes, too big to store in a ConfigMap", len(bundle)))
+		return
+	}
+
+	cm := cluster.TeardownLogsConfigMap(bundle, time.Now())
+	err = r.Create(context.TODO(), cm)
+	if err != nil && errors.IsAlreadyExists(err) {
+		err = r.Update(context.TODO(), cm)
+	}
+	if err != nil {
+		log.Info(fmt.Sprintf("Can't store exported logs: %s", err.Error()))
+		return
+	}
+	log.Info(fmt.Sprintf("Stored exported logs in ConfigMap %s/%s", cm.Namespace, cm.Name))
+	r.Recorder.Event(cluster, v1.EventTypeNormal, "LogsExported", fmt.Sprintf("Stored exported logs in ConfigMap %s", cm.Name))
+}
+
+// captureBootstrapLogs keeps the evidence of a failed Cluster pod in a ConfigMap so it
+// can still be read once the pod has been recreated. Exporting the nested cluster's logs
+// can take minutes, so like the teardown it runs in the background inside the pod and is
+// polled. It returns whether the logs have been captured and the pod can be let go.
+func (r *ClusterReconciler) captureBootstrapLogs(cluster *honkv1.Cluster, pod *v1.Pod) (ctrl.Result, bool) {
+	log := r.Log.WithValues("cluster", types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		log.Info("Can't get config from ctrl")
+		return ctrl.Result{}, true
+	}
+
+	// The nested cluster's own logs can only be exported while the container is still running
+	var bundle []byte
+	if pod.Status.Phase == v1.PodRunning {
+		var done bool
+		if bundle, done = r.exportLogs(cluster, config); !done {
+			return ctrl.Result{RequeueAfter: cluster.RequeueBackoff(0)}, false
+		}
+	}
+	cluster.Status.LogExportStartedAt = nil
+
+	tail, err := cluster.ContainerLogTail(config, bootstrapLogTailLines)
+	if err != nil {
+		log.Info(fmt.Sprintf("Can't read the container log: %s", err.Error()))
+	}
+	if len(bundle)+len(tail) > maxLogBundleSize {
+		log.Info(fmt.Sprintf("Exported logs are %d bytes, too big to store in a ConfigMap", len(bundle)))
+		bundle = nil
+	}
+
+	if tail == "" && len(bundle) == 0 {
+		return ctrl.Result{}, true
+	}
+
+	cm := cluster.LogsConfigMap("bootstrap-logs", bundle)
+	if len(bundle) == 0 {
+		cm.BinaryData = nil
+	}
+	cm.Data = map[string]string{
+		"container.log": tail,
+	}
+	err = r.Create(context.TODO(), cm)
+	if err != nil && errors.IsAlreadyExists(err) {
+		err = r.Update(context.TODO(), cm)
+	}
+	if err != nil {
+		log.Info(fmt.Sprintf("Can't store bootstrap logs: %s", err.Error()))
+		return ctrl.Result{}, true
+	}
+
+	cluster.Status.BootstrapLogs = cm.Name
+	r.Recorder.Event(cluster, v1.EventTypeNormal, "LogsCaptured", fmt.Sprintf("Stored the failed pod's logs in ConfigMap %s", cm.Name))
+	return ctrl.Result{}, true
+}
+
+// exportLogs starts exporting the nested cluster's logs in the background inside the Cluster
+// pod and then polls for the bundle. It returns whether it's done, with the bundle if there is one.
+func (r *ClusterReconciler) exportLogs(cluster *honkv1.Cluster, config *rest.Config) ([]byte, bool) {
+	log := r.Log.WithValues("cluster", types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace})
+
+	if cluster.Status.LogExportStartedAt == nil {
+		err := cluster.StartLogExport(config)
+		observeExec("export-logs-start", err)
+		if err != nil {
+			log.Info(fmt.Sprintf("Can't export logs: %s", err.Error()))
+			return nil, true
+		}
+		now := metav1.Now()
+		cluster.Status.LogExportStartedAt = &now
+		return nil, false
+	}
+
+	// The pod kills the export after the timeout, this covers it never reporting back
+	deadline := cluster.Status.LogExportStartedAt.Add(cluster.TeardownTimeout() + teardownGracePeriod)
+	finished, err := cluster.LogExportFinished(config)
+	observeExec("export-logs-progress", err)
+	if err != nil || !finished {
+		if time.Now().After(deadline) {
+			log.Info("Gave up waiting for the logs to be exported")
+			return nil, true
+		}
+		return nil, false
+	}
+
+	bundle, err := cluster.ExportedLogs(config)
+	observeExec("export-logs", err)
+	if err != nil {
+		log.Info(fmt.Sprintf("Can't export logs: %s", err.Error()))
+		return nil, true
+	}
+	return bundle, true
+}
+
+// recordPhaseChange emits an Event, and counts failures, when the Cluster becomes ready or fails
+func (r *ClusterReconciler) recordPhaseChange(cluster *honkv1.Cluster, previous honkv1.ClusterPhase) {
+	if cluster.Status.Phase == previous {
+		return
+	}
+
+	message, reason := "", ""
+	if condition := cluster.GetCondition(honkv1.ClusterReady); condition != nil {
+		message, reason = condition.Message, condition.Reason
+	}
+
+	switch cluster.Status.Phase {
+	case honkv1.ClusterPhaseReady:
+		r.Recorder.Event(cluster, v1.EventTypeNormal, "Ready", fmt.Sprintf("The %s cluster is ready", cluster.Spec.ClusterType))
+	case honkv1.ClusterPhaseDegraded:
+		r.Recorder.Event(cluster, v1.EventTypeWarning, "Degraded", message)
+	case honkv1.ClusterPhaseFailed:
+		clusterProvisioningFailures.WithLabelValues(string(cluster.Spec.ClusterType), reason).Inc()
+		r.Recorder.Event(cluster, v1.EventTypeWarning, "Failed", message)
+	case honkv1.ClusterPhaseTerminating:
+		r.Recorder.Event(cluster, v1.EventTypeNormal, "Terminating", message)
+	}
+}
+
+// setNotReady moves the Cluster into a non-ready phase, explaining why on the Ready condition
+func setNotReady(cluster *honkv1.Cluster, phase honkv1.ClusterPhase, reason, message string) {
+	cluster.Status.Ready = false
+	cluster.Status.Phase = phase
+	cluster.SetCondition(honkv1.ClusterReady, v1.ConditionFalse, reason, message)
+}
+
+// resetProvisioning forgets everything learned from the previous pod and marks every
+// provisioning step as not done, since a fresh pod has to go through them again
+func resetProvisioning(cluster *honkv1.Cluster, reason string) {
+	cluster.Status.ProvisioningStartedAt = nil
+	cluster.Status.ReadyAt = nil
+	cluster.Status.KubernetesVersion = ""
+	cluster.Status.NodeCount = 0
+	cluster.Status.APIEndpoint = ""
+	cluster.Status.ToolVersions = nil
+	cluster.Status.LogExportStartedAt = nil
+
+	cluster.SetCondition(honkv1.ClusterPodScheduled, v1.ConditionFalse, reason, "")
+	cluster.SetCondition(honkv1.ClusterBootstrapped, v1.ConditionFalse, reason, "")
+	cluster.SetCondition(honkv1.ClusterManifestsApplied, v1.ConditionFalse, reason, "")
+	cluster.SetCondition(honkv1.ClusterKubeconfigReady, v1.ConditionFalse, reason, "")
+	cluster.SetCondition(honkv1.ClusterHealthy, v1.ConditionUnknown, reason, "")
+}
+
+// setPodScheduled mirrors the pod's own PodScheduled condition onto the Cluster
+func setPodScheduled(cluster *honkv1.Cluster, pod *v1.Pod) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled {
+			reason := condition.Reason
+			if reason == "" {
+				// the kubelet leaves the reason empty once a pod is scheduled, but ClusterCondition requires one
+				reason = "PodScheduled"
+			}
+			cluster.SetCondition(honkv1.ClusterPodScheduled, condition.Status, reason, condition.Message)
+			return
+		}
+	}
+	cluster.SetCondition(honkv1.ClusterPodScheduled, v1.ConditionFalse, "PodPending", "")
+}
+
+// setProgress records which bootstrap steps have finished in a pod that isn't ready yet
+func setProgress(cluster *honkv1.Cluster, bootstrapped, manifestsApplied bool) {
+	switch {
+	case !bootstrapped:
+		setNotReady(cluster, honkv1.ClusterPhaseBootstrapping, "Bootstrapping", fmt.Sprintf("Creating the %s cluster", cluster.Spec.ClusterType))
+		cluster.SetCondition(honkv1.ClusterBootstrapped, v1.ConditionFalse, "Bootstrapping", "")
+		cluster.SetCondition(honkv1.ClusterManifestsApplied, v1.ConditionFalse, "Bootstrapping", "")
+	case !manifestsApplied:
+		setNotReady(cluster, honkv1.ClusterPhaseApplyingManifests, "ApplyingManifests", fmt.Sprintf("Applying %d clusterYAML manifests", len(cluster.Spec.ClusterYAML)))
+		cluster.SetCondition(honkv1.ClusterBootstrapped, v1.ConditionTrue, "Bootstrapped", "")
+		cluster.SetCondition(honkv1.ClusterManifestsApplied, v1.ConditionFalse, "ApplyingManifests", "")
+	default:
+		setNotReady(cluster, honkv1.ClusterPhaseFetchingKubeconfig, "WaitingForReadiness", "Waiting for the Cluster pod to report ready")
+		cluster.SetCondition(honkv1.ClusterBootstrapped, v1.ConditionTrue, "Bootstrapped", "")
+		cluster.SetCondition(honkv1.ClusterManifestsApplied, v1.ConditionTrue, "ManifestsApplied", "")
+	}
+}
+
+// requeueWhileProvisioning works out when to look at a Cluster that isn't ready yet,
+// treating the pod as failed once it has been provisioning for longer than the deadline.
+// The deadline only applies until the Cluster has been ready once.
+func (r *ClusterReconciler) requeueWhileProvisioning(cluster *honkv1.Cluster, pod *v1.Pod) (ctrl.Result, error) {
+	elapsed := time.Since(pod.CreationTimestamp.Time)
+	if timeout := cluster.ProvisioningTimeout(); cluster.Status.ReadyAt == nil && elapsed > timeout {
+		return r.handleFailure(cluster, pod, "ProvisioningTimeout", fmt.Sprintf("Cluster did not become ready within %s, last phase was %s", timeout, cluster.Status.Phase))
+	}
+
+	return ctrl.Result{RequeueAfter: cluster.RequeueBackoff(elapsed)}, nil
+}
+
+// handleFailure recreates a failed Cluster pod if the restart policy allows another
+// attempt, otherwise it gives up and leaves the Cluster Failed with the last error
+func (r *ClusterReconciler) handleFailure(cluster *honkv1.Cluster, pod *v1.Pod, reason, message string) (ctrl.Result, error) {
+	cluster.Status.LastError = message
+	if cluster.Status.Phase != honkv1.ClusterPhaseFailed {
+		// Hold on to the pod until its logs are safe, it's the only place they live
+		if result, done := r.captureBootstrapLogs(cluster, pod); !done {
+			return result, nil
+		}
+	}
+	if !cluster.CanRetry() {
+		setNotReady(cluster, honkv1.ClusterPhaseFailed, reason, message)
+		return ctrl.Result{}, nil
+	}
+
+	cluster.Status.Attempts++
+	r.Recorder.Event(cluster, v1.EventTypeWarning, "Retrying", fmt.Sprintf("Recreating Pod %s, attempt %d: %s", pod.GetName(), cluster.Status.Attempts, message))
+	setNotReady(cluster, honkv1.ClusterPhasePending, "PodRecreating", fmt.Sprintf("The Cluster pod failed and is being recreated: %s", message))
+	resetProvisioning(cluster, "PodRecreating")
+
+	err := r.Delete(context.TODO(), pod)
+	return ctrl.Result{RequeueAfter: cluster.RequeueBackoff(0)}, client.IgnoreNotFound(err)
+}
+
+// secretDataEquals returns whether a stored Secret already holds exactly the given data
+func secretDataEquals(secret *v1.Secret, data map[string]string) bool {
+	if len(secret.Data) != len(data) {
+		return false
+	}
+	for k, v := range data {
+		if string(secret.Data[k]) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// hasFinalizer returns whether the object carries the given finalizer
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// podMessage summarises why a pod is in its current phase
+func podMessage(pod *v1.Pod) string {
+	if pod.Status.Message != "" {
+		return pod.Status.Message
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return fmt.Sprintf("%s: %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+		}
+		if status.State.Terminated != nil {
+			return fmt.Sprintf("%s (exit code %d): %s", status.State.Terminated.Reason, status.State.Terminated.ExitCode, status.State.Terminated.Message)
+		}
+	}
+	return fmt.Sprintf("Pod is %s", pod.Status.Phase)
+}
+
+// containerReady returns whether the named container in the pod is passing its readiness probe
+func containerReady(pod *v1.Pod, name string) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == name {
+			return status.Ready
+		}
+	}
+	return false
+}
+
+// containerRestarts returns how many times the pod's containers have restarted, and why the last one stopped
+func containerRestarts(pod *v1.Pod) (int32, string) {
+	var restarts int32
+	message := ""
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			message = fmt.Sprintf("%s (exit code %d): %s", terminated.Reason, terminated.ExitCode, terminated.Message)
+		}
+	}
+	return restarts, message
+}
+
+// terminatedContainer returns the first of the pod's containers that has exited, and why.
+// Every container in a Cluster pod is meant to run for as long as the pod does.
+func terminatedContainer(pod *v1.Pod) (string, string) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil {
+			return status.Name, fmt.Sprintf("%s (exit code %d): %s", terminated.Reason, terminated.ExitCode, terminated.Message)
+		}
+	}
+	return "", ""
+}
+
+const (
+	// teardownGracePeriod is how long past the teardown timeout a teardown that never reports back is waited for
+	teardownGracePeriod = time.Minute
+	// maxLogBundleSize keeps log bundles under the 1MiB object size limit
+	maxLogBundleSize = 1000 * 1024
+	// bootstrapLogTailLines is how much of a failed Cluster pod's container log is kept
+	bootstrapLogTailLines = 200
+	// serverPodAnnotation records the UID of the Cluster pod an agent pod joined
+	serverPodAnnotation = "honk.honk.ci/server-pod"
+)
+
 var (
 	jobOwnerKey = ".metadata.controller"
 	apiGVStr    = honkv1.GroupVersion.String()
@@ -209,6 +1049,20 @@ var (
 
 // SetupWithManager sets up the controller manager :tada:
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := metrics.Registry.Register(&clusterCollector{client: mgr.GetClient()}); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return err
+		}
+	}
+
+	if err := mgr.Add(&teardownLogsReaper{
+		client:   mgr.GetClient(),
+		log:      r.Log.WithName("teardown-logs"),
+		interval: teardownLogsReapInterval,
+	}); err != nil {
+		return err
+	}
+
 	// Index the Cluster-Pods
 	if err := mgr.GetFieldIndexer().IndexField(&v1.Pod{}, jobOwnerKey, func(rawObj runtime.Object) []string {
 		pod := rawObj.(*v1.Pod)
@@ -248,5 +1102,6 @@ func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&v1.Service{}).
 		Owns(&v1.Pod{}).
 		Owns(&v1.ConfigMap{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
@@ -0,0 +1,288 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+// newCluster returns a kind Cluster with what the defaulting webhook would have filled in
+func newCluster(name string) *honkv1.Cluster {
+	cpu := resource.MustParse("500m")
+	memory := resource.MustParse("1Gi")
+	return &honkv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: honkv1.ClusterSpec{
+			ClusterType: honkv1.KindCluster,
+			CPU:         &cpu,
+			Memory:      &memory,
+		},
+	}
+}
+
+func key(cluster *honkv1.Cluster) types.NamespacedName {
+	return types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}
+}
+
+var _ = Describe("ClusterReconciler", func() {
+	ctx := context.Background()
+	var r *ClusterReconciler
+
+	reconcile := func(cluster *honkv1.Cluster) (ctrl.Result, error) {
+		return r.Reconcile(ctrl.Request{NamespacedName: key(cluster)})
+	}
+
+	BeforeEach(func() {
+		r = &ClusterReconciler{
+			Client:   k8sClient,
+			Log:      logf.Log.WithName("controllers").WithName("Cluster"),
+			Scheme:   scheme.Scheme,
+			Recorder: &record.FakeRecorder{},
+		}
+	})
+
+	Context("when a Cluster expires", func() {
+		It("deletes it and releases it once there's nothing to tear down", func() {
+			cluster := newCluster("expired")
+			expiresAt := metav1.NewTime(time.Now().Add(-time.Minute))
+			cluster.Spec.ExpiresAt = &expiresAt
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+
+			_, err := reconcile(cluster)
+			Expect(err).NotTo(HaveOccurred())
+
+			// The teardown finalizer holds on to the deleted Cluster
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.DeletionTimestamp).NotTo(BeNil())
+			Expect(cluster.Finalizers).To(ContainElement(honkv1.TeardownFinalizer))
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{Requeue: true}))
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Status.Phase).To(Equal(honkv1.ClusterPhaseTerminating))
+
+			// There's no Cluster pod, so nothing to tear down
+			_, err = reconcile(cluster)
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, key(cluster), cluster)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("keeps it while deletion protection is enabled", func() {
+			cluster := newCluster("expired-protected")
+			expiresAt := metav1.NewTime(time.Now().Add(-time.Minute))
+			cluster.Spec.ExpiresAt = &expiresAt
+			cluster.Spec.DeletionProtection = true
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+
+			_, err := reconcile(cluster)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.DeletionTimestamp).To(BeNil())
+			Expect(cluster.IsConditionTrue(honkv1.ClusterDeletionBlocked)).To(BeTrue())
+			Expect(cluster.GetCondition(honkv1.ClusterDeletionBlocked).Reason).To(Equal("Expired"))
+		})
+	})
+
+	// runningPod creates the Cluster's pod as the kubelet would report it running the given containers
+	runningPod := func(cluster *honkv1.Cluster, statuses ...v1.ContainerStatus) *v1.Pod {
+		pod := cluster.Pod(cluster.Namespace)
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		for i := range statuses {
+			statuses[i].Image = pod.Spec.Containers[i].Image
+		}
+		pod.Status.Phase = v1.PodRunning
+		pod.Status.ContainerStatuses = statuses
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		return pod
+	}
+	exited := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}
+
+	Context("when a container in the Cluster pod exits", func() {
+		It("fails the Cluster even though the pod is still running", func() {
+			cluster := newCluster("etcd-exited")
+			cluster.Spec.ClusterType = honkv1.ControlPlaneCluster
+			cluster.Finalizers = []string{honkv1.TeardownFinalizer}
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+			runningPod(cluster, v1.ContainerStatus{Name: "bootstrap"}, v1.ContainerStatus{Name: "etcd", State: exited})
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{}))
+
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Status.Phase).To(Equal(honkv1.ClusterPhaseFailed))
+			Expect(cluster.GetCondition(honkv1.ClusterReady).Reason).To(Equal("ContainerTerminated"))
+			Expect(cluster.Status.LastError).To(ContainSubstring("etcd"))
+		})
+
+		It("recreates the pod while the restart policy allows it", func() {
+			cluster := newCluster("etcd-exited-recreate")
+			cluster.Spec.ClusterType = honkv1.ControlPlaneCluster
+			cluster.Spec.RestartPolicy = honkv1.RestartPolicyRecreate
+			cluster.Finalizers = []string{honkv1.TeardownFinalizer}
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+			pod := runningPod(cluster, v1.ContainerStatus{Name: "bootstrap"}, v1.ContainerStatus{Name: "etcd", State: exited})
+
+			result, err := reconcile(cluster)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Status.Phase).To(Equal(honkv1.ClusterPhasePending))
+			Expect(cluster.Status.Attempts).To(Equal(int32(1)))
+			err = k8sClient.Get(ctx, key(cluster), pod)
+			Expect(errors.IsNotFound(err) || pod.DeletionTimestamp != nil).To(BeTrue())
+		})
+
+		It("fails pods that restart their containers", func() {
+			cluster := newCluster("restarted")
+			cluster.Finalizers = []string{honkv1.TeardownFinalizer}
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+
+			// Created before Cluster pods stopped restarting their containers
+			pod := cluster.Pod(cluster.Namespace)
+			pod.Spec.RestartPolicy = v1.RestartPolicyAlways
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.Phase = v1.PodRunning
+			pod.Status.ContainerStatuses = []v1.ContainerStatus{{Name: cluster.PrimaryContainer(), Image: pod.Spec.Containers[0].Image, RestartCount: 2, LastTerminationState: exited}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{}))
+
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Status.Phase).To(Equal(honkv1.ClusterPhaseFailed))
+			Expect(cluster.GetCondition(honkv1.ClusterReady).Reason).To(Equal("ContainerRestarted"))
+		})
+	})
+
+	Context("when a Cluster has been ready", func() {
+		var config *honkv1.KaasConfig
+
+		BeforeEach(func() {
+			namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: honkv1.KaasConfigNamespace}}
+			if err := k8sClient.Create(ctx, namespace); err != nil {
+				Expect(errors.IsAlreadyExists(err)).To(BeTrue())
+			}
+
+			// Every pod is past this deadline
+			config = &honkv1.KaasConfig{
+				ObjectMeta:          metav1.ObjectMeta{Name: honkv1.KaasConfigName, Namespace: honkv1.KaasConfigNamespace},
+				ProvisioningTimeout: &metav1.Duration{Duration: time.Nanosecond},
+				HealthCheckInterval: &metav1.Duration{Duration: 30 * time.Second},
+			}
+			Expect(k8sClient.Create(ctx, config)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, config)).To(Succeed())
+		})
+
+		It("Ready pod goes unready after the deadline", func() {
+			cluster := newCluster("unready")
+			cluster.Spec.RestartPolicy = honkv1.RestartPolicyRecreate
+			cluster.Finalizers = []string{honkv1.TeardownFinalizer}
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+
+			readyAt := metav1.NewTime(time.Now().Add(-time.Hour))
+			cluster.Status.ReadyAt = &readyAt
+			cluster.Status.Ready = true
+			cluster.Status.Phase = honkv1.ClusterPhaseReady
+			cluster.SetCondition(honkv1.ClusterHealthy, v1.ConditionTrue, "HealthCheckPassed", "")
+			Expect(k8sClient.Status().Update(ctx, cluster)).To(Succeed())
+
+			pod := cluster.SetConfig(config).Pod(cluster.Namespace)
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status.Phase = v1.PodRunning
+			pod.Status.ContainerStatuses = []v1.ContainerStatus{{Name: cluster.PrimaryContainer(), Image: pod.Spec.Containers[0].Image, Ready: false}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{RequeueAfter: 30 * time.Second}))
+
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Status.Phase).To(Equal(honkv1.ClusterPhaseDegraded))
+			Expect(cluster.Status.Ready).To(BeFalse())
+			Expect(cluster.Status.Attempts).To(BeZero())
+			Expect(cluster.GetCondition(honkv1.ClusterHealthy).Reason).To(Equal("PodNotReady"))
+
+			// The nested cluster is left alone to recover
+			Expect(k8sClient.Get(ctx, key(cluster), pod)).To(Succeed())
+			Expect(pod.DeletionTimestamp).To(BeNil())
+		})
+	})
+
+	Context("when a Cluster is deleted", func() {
+		It("holds it while deletion protection is enabled", func() {
+			cluster := newCluster("protected")
+			cluster.Spec.DeletionProtection = true
+			cluster.Finalizers = []string{honkv1.TeardownFinalizer, honkv1.DeletionProtectionFinalizer}
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, cluster)).To(Succeed())
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{}))
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.IsConditionTrue(honkv1.ClusterDeletionBlocked)).To(BeTrue())
+			Expect(cluster.Finalizers).To(ContainElement(honkv1.DeletionProtectionFinalizer))
+
+			cluster.Spec.DeletionProtection = false
+			Expect(k8sClient.Update(ctx, cluster)).To(Succeed())
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{Requeue: true}))
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Finalizers).To(Equal([]string{honkv1.TeardownFinalizer}))
+
+			_, err := reconcile(cluster)
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, key(cluster), cluster)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("releases it without a teardown while the pod isn't running", func() {
+			cluster := newCluster("pending")
+			cluster.Finalizers = []string{honkv1.TeardownFinalizer}
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+			Expect(k8sClient.Create(ctx, cluster.Pod(cluster.Namespace))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, cluster)).To(Succeed())
+
+			Expect(reconcile(cluster)).To(Equal(ctrl.Result{Requeue: true}))
+			Expect(k8sClient.Get(ctx, key(cluster), cluster)).To(Succeed())
+			Expect(cluster.Status.Phase).To(Equal(honkv1.ClusterPhaseTerminating))
+
+			// The pod is still Pending, so there's no nested cluster to tear down
+			_, err := reconcile(cluster)
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, key(cluster), cluster)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
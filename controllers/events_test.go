@@ -0,0 +1,87 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+var _ = Describe("Cluster Events", func() {
+	ctx := context.Background()
+
+	DescribeTable("recordPhaseChange",
+		func(previous, phase honkv1.ClusterPhase, message string, want []string) {
+			recorder := record.NewFakeRecorder(10)
+			r := &ClusterReconciler{Recorder: recorder}
+			cluster := &honkv1.Cluster{}
+			cluster.Spec.ClusterType = honkv1.KindCluster
+			cluster.Status.Phase = phase
+			cluster.SetCondition(honkv1.ClusterReady, v1.ConditionFalse, "Reason", message)
+
+			r.recordPhaseChange(cluster, previous)
+
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			Expect(events).To(Equal(want))
+		},
+		Entry("no change", honkv1.ClusterPhaseFailed, honkv1.ClusterPhaseFailed, "kind create cluster failed", nil),
+		Entry("becomes ready", honkv1.ClusterPhaseFetchingKubeconfig, honkv1.ClusterPhaseReady, "", []string{"Normal Ready The kind cluster is ready"}),
+		Entry("degrades", honkv1.ClusterPhaseReady, honkv1.ClusterPhaseDegraded, "1 nodes not Ready", []string{"Warning Degraded 1 nodes not Ready"}),
+		Entry("fails", honkv1.ClusterPhaseBootstrapping, honkv1.ClusterPhaseFailed, "kind create cluster failed", []string{"Warning Failed kind create cluster failed"}),
+		Entry("starts terminating", honkv1.ClusterPhaseReady, honkv1.ClusterPhaseTerminating, "Tearing down the kind cluster", []string{"Normal Terminating Tearing down the kind cluster"}),
+		Entry("makes progress", honkv1.ClusterPhasePending, honkv1.ClusterPhaseBootstrapping, "Creating the kind cluster", nil),
+	)
+
+	DescribeTable("expired Clusters",
+		func(name string, deletionProtection bool, want string) {
+			recorder := record.NewFakeRecorder(10)
+			r := &ClusterReconciler{
+				Client:   k8sClient,
+				Log:      logf.Log.WithName("controllers").WithName("Cluster"),
+				Scheme:   scheme.Scheme,
+				Recorder: recorder,
+			}
+			cluster := newCluster(name)
+			expiresAt := metav1.NewTime(time.Now().Add(-time.Minute))
+			cluster.Spec.ExpiresAt = &expiresAt
+			cluster.Spec.DeletionProtection = deletionProtection
+			Expect(k8sClient.Create(ctx, cluster)).To(Succeed())
+
+			_, err := r.Reconcile(ctrl.Request{NamespacedName: key(cluster)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(Receive(HavePrefix(want)))
+		},
+		Entry("are deleted", "expired-event", false, "Normal Expired "),
+		Entry("are kept while deletion protection is enabled", "expired-protected-event", true, "Warning DeletionBlocked "),
+	)
+})
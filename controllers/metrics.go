@@ -0,0 +1,123 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+var (
+	// clusterTimeToReady tracks how long it takes from creating a Cluster pod to the Cluster being ready
+	clusterTimeToReady = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kaas_cluster_time_to_ready_seconds",
+		Help:    "Time from creating a Cluster pod until the Cluster is ready, per cluster type",
+		Buckets: []float64{30, 60, 90, 120, 180, 240, 300, 450, 600, 900, 1200, 1800},
+	}, []string{"cluster_type"})
+
+	// clusterProvisioningFailures counts Clusters moving into the Failed phase
+	clusterProvisioningFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kaas_cluster_provisioning_failures_total",
+		Help: "Total number of Clusters that failed to provision, per cluster type and reason",
+	}, []string{"cluster_type", "reason"})
+
+	// clusterPodRecreations counts Cluster pods deleted because they drifted from the spec
+	clusterPodRecreations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kaas_cluster_pod_recreations_total",
+		Help: "Total number of Cluster pods recreated, per cluster type and the part of the pod that changed",
+	}, []string{"cluster_type", "field"})
+
+	// kubeconfigFetchTime tracks how long it takes to copy the kubeconfigs out of a Cluster pod
+	kubeconfigFetchTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "kaas_kubeconfig_fetch_time_seconds",
+		Help: "Length of time to fetch the kubeconfigs from a Cluster pod, per cluster type",
+	}, []string{"cluster_type"})
+
+	// kubeconfigFetchErrors counts failed attempts to fetch kubeconfigs
+	kubeconfigFetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kaas_kubeconfig_fetch_errors_total",
+		Help: "Total number of errors fetching kubeconfigs from a Cluster pod, per cluster type",
+	}, []string{"cluster_type"})
+
+	// execCalls counts commands exec'd into Cluster pods
+	execCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kaas_exec_calls_total",
+		Help: "Total number of exec calls into Cluster pods, per operation and result",
+	}, []string{"operation", "result"})
+
+	clustersDesc = prometheus.NewDesc(
+		"kaas_clusters",
+		"Number of Clusters per cluster type and phase",
+		[]string{"cluster_type", "phase"}, nil)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		clusterTimeToReady,
+		clusterProvisioningFailures,
+		clusterPodRecreations,
+		kubeconfigFetchTime,
+		kubeconfigFetchErrors,
+		execCalls,
+	)
+}
+
+// observeExec counts an exec call into a Cluster pod
+func observeExec(operation string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	execCalls.WithLabelValues(operation, result).Inc()
+}
+
+// clusterCollector counts the Clusters of each type and phase whenever metrics are scraped
+type clusterCollector struct {
+	client client.Reader
+}
+
+// Describe implements prometheus.Collector
+func (c *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clustersDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	var clusters honkv1.ClusterList
+	if err := c.client.List(context.Background(), &clusters); err != nil {
+		ch <- prometheus.NewInvalidMetric(clustersDesc, err)
+		return
+	}
+
+	type key struct {
+		clusterType honkv1.ClusterType
+		phase       honkv1.ClusterPhase
+	}
+	counts := make(map[key]float64)
+	for _, cluster := range clusters.Items {
+		counts[key{cluster.Spec.ClusterType, cluster.Status.Phase}]++
+	}
+
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(clustersDesc, prometheus.GaugeValue, count, string(k.clusterType), string(k.phase))
+	}
+}
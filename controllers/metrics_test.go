@@ -0,0 +1,89 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+var _ = Describe("Metrics", func() {
+	DescribeTable("observeExec",
+		func(operation string, err error, result string) {
+			counter := execCalls.WithLabelValues(operation, result)
+			before := testutil.ToFloat64(counter)
+
+			observeExec(operation, err)
+
+			Expect(testutil.ToFloat64(counter) - before).To(Equal(1.0))
+		},
+		Entry("success", "progress", nil, "success"),
+		Entry("error", "kubeconfig", fmt.Errorf("container not found"), "error"),
+	)
+
+	It("counts Clusters that fail", func() {
+		counter := clusterProvisioningFailures.WithLabelValues(string(honkv1.K3sCluster), "PodFailed")
+		before := testutil.ToFloat64(counter)
+
+		r := &ClusterReconciler{Recorder: record.NewFakeRecorder(10)}
+		cluster := &honkv1.Cluster{}
+		cluster.Spec.ClusterType = honkv1.K3sCluster
+		cluster.Status.Phase = honkv1.ClusterPhaseFailed
+		cluster.SetCondition(honkv1.ClusterReady, v1.ConditionFalse, "PodFailed", "")
+
+		r.recordPhaseChange(cluster, honkv1.ClusterPhaseBootstrapping)
+		r.recordPhaseChange(cluster, honkv1.ClusterPhaseFailed)
+
+		Expect(testutil.ToFloat64(counter) - before).To(Equal(1.0))
+	})
+
+	It("counts Clusters per type and phase", func() {
+		cluster := func(name string, clusterType honkv1.ClusterType, phase honkv1.ClusterPhase) runtime.Object {
+			c := &honkv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			c.Spec.ClusterType = clusterType
+			c.Status.Phase = phase
+			return c
+		}
+		collector := &clusterCollector{client: fake.NewFakeClientWithScheme(scheme.Scheme,
+			cluster("a", honkv1.KindCluster, honkv1.ClusterPhaseReady),
+			cluster("b", honkv1.KindCluster, honkv1.ClusterPhaseReady),
+			cluster("c", honkv1.KindCluster, honkv1.ClusterPhaseFailed),
+			cluster("d", honkv1.K3sCluster, honkv1.ClusterPhaseReady),
+		)}
+
+		Expect(testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP kaas_clusters Number of Clusters per cluster type and phase
+# TYPE kaas_clusters gauge
+kaas_clusters{cluster_type="k3s",phase="Ready"} 1
+kaas_clusters{cluster_type="kind",phase="Failed"} 1
+kaas_clusters{cluster_type="kind",phase="Ready"} 2
+`))).To(Succeed())
+	})
+})
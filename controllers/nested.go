@@ -0,0 +1,68 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// nestedHealth counts the nested cluster's nodes that aren't Ready and names the kube-system pods that are failing
+func nestedHealth(nodes *v1.NodeList, pods *v1.PodList) (notReadyNodes int, failingPods []string) {
+	for _, node := range nodes.Items {
+		if !nodeReady(node) {
+			notReadyNodes++
+		}
+	}
+
+	for _, pod := range pods.Items {
+		if podFailing(pod) {
+			failingPods = append(failingPods, pod.Name)
+		}
+	}
+
+	return notReadyNodes, failingPods
+}
+
+// nodeReady returns whether the node's Ready condition is True
+func nodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podFailing returns whether a pod has failed or has a container stuck crashing or pulling its image
+func podFailing(pod v1.Pod) bool {
+	switch pod.Status.Phase {
+	case v1.PodFailed, v1.PodUnknown:
+		return true
+	case v1.PodSucceeded:
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+		switch status.State.Waiting.Reason {
+		case "CrashLoopBackOff", "ErrImagePull", "ImagePullBackOff":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("nestedHealth", func() {
+	node := func(ready v1.ConditionStatus) v1.Node {
+		return v1.Node{Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}}}}
+	}
+	pod := func(name string, phase v1.PodPhase, waiting string) v1.Pod {
+		p := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: v1.PodStatus{Phase: phase}}
+		if waiting != "" {
+			p.Status.ContainerStatuses = []v1.ContainerStatus{{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: waiting}}}}
+		}
+		return p
+	}
+
+	It("counts nodes that aren't Ready", func() {
+		nodes := &v1.NodeList{Items: []v1.Node{node(v1.ConditionTrue), node(v1.ConditionFalse), {}}}
+		notReady, failing := nestedHealth(nodes, &v1.PodList{})
+		Expect(notReady).To(Equal(2))
+		Expect(failing).To(BeEmpty())
+	})
+
+	It("names the failing kube-system pods", func() {
+		pods := &v1.PodList{Items: []v1.Pod{
+			pod("coredns", v1.PodRunning, ""),
+			pod("kube-proxy", v1.PodRunning, "CrashLoopBackOff"),
+			pod("metrics-server", v1.PodPending, "ImagePullBackOff"),
+			pod("install-cni", v1.PodSucceeded, ""),
+			pod("etcd", v1.PodFailed, ""),
+			pod("scheduler", v1.PodPending, "ContainerCreating"),
+		}}
+		notReady, failing := nestedHealth(&v1.NodeList{Items: []v1.Node{node(v1.ConditionTrue)}}, pods)
+		Expect(notReady).To(BeZero())
+		Expect(failing).To(Equal([]string{"kube-proxy", "metrics-server", "etcd"}))
+	})
+})
@@ -0,0 +1,81 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+var _ = Describe("Cluster status", func() {
+	DescribeTable("setProgress",
+		func(bootstrapped, manifestsApplied bool, phase honkv1.ClusterPhase, wantBootstrapped, wantManifestsApplied v1.ConditionStatus) {
+			cluster := &honkv1.Cluster{}
+			setProgress(cluster, bootstrapped, manifestsApplied)
+
+			Expect(cluster.Status.Phase).To(Equal(phase))
+			Expect(cluster.Status.Ready).To(BeFalse())
+			Expect(cluster.GetCondition(honkv1.ClusterReady).Status).To(Equal(v1.ConditionFalse))
+			Expect(cluster.GetCondition(honkv1.ClusterBootstrapped).Status).To(Equal(wantBootstrapped))
+			Expect(cluster.GetCondition(honkv1.ClusterManifestsApplied).Status).To(Equal(wantManifestsApplied))
+		},
+		Entry("nothing done yet", false, false, honkv1.ClusterPhaseBootstrapping, v1.ConditionFalse, v1.ConditionFalse),
+		Entry("bootstrapped", true, false, honkv1.ClusterPhaseApplyingManifests, v1.ConditionTrue, v1.ConditionFalse),
+		Entry("manifests applied", true, true, honkv1.ClusterPhaseFetchingKubeconfig, v1.ConditionTrue, v1.ConditionTrue),
+	)
+
+	DescribeTable("setPodScheduled",
+		func(conditions []v1.PodCondition, want v1.ConditionStatus, reason string) {
+			cluster := &honkv1.Cluster{}
+			setPodScheduled(cluster, &v1.Pod{Status: v1.PodStatus{Conditions: conditions}})
+
+			condition := cluster.GetCondition(honkv1.ClusterPodScheduled)
+			Expect(condition.Status).To(Equal(want))
+			Expect(condition.Reason).To(Equal(reason))
+		},
+		Entry("not scheduled yet", nil, v1.ConditionFalse, "PodPending"),
+		Entry("scheduled", []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}}, v1.ConditionTrue, "PodScheduled"),
+		Entry("unschedulable", []v1.PodCondition{
+			{Type: v1.PodInitialized, Status: v1.ConditionTrue},
+			{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: "Unschedulable"},
+		}, v1.ConditionFalse, "Unschedulable"),
+	)
+
+	DescribeTable("podMessage",
+		func(status v1.PodStatus, want string) {
+			Expect(podMessage(&v1.Pod{Status: status})).To(Equal(want))
+		},
+		Entry("pod message", v1.PodStatus{Phase: v1.PodFailed, Message: "Pod was evicted"}, "Pod was evicted"),
+		Entry("waiting container", v1.PodStatus{
+			Phase: v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{
+				Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "can't pull"},
+			}}},
+		}, "ImagePullBackOff: can't pull"),
+		Entry("terminated container", v1.PodStatus{
+			Phase: v1.PodFailed,
+			ContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{Reason: "Error", ExitCode: 1, Message: "kind failed"},
+			}}},
+		}, "Error (exit code 1): kind failed"),
+		Entry("nothing to go on", v1.PodStatus{Phase: v1.PodPending}, "Pod is Pending"),
+	)
+})
@@ -0,0 +1,81 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+// teardownLogsReapInterval is how often expired teardown logs are looked for
+const teardownLogsReapInterval = time.Hour
+
+// teardownLogsReaper deletes the teardown logs ConfigMaps whose TTL has run out. They outlive
+// their Cluster on purpose, so nothing else ever cleans them up.
+type teardownLogsReaper struct {
+	client   client.Client
+	log      logr.Logger
+	interval time.Duration
+}
+
+// Start implements manager.Runnable, reaping every interval until stop is closed
+func (r *teardownLogsReaper) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.reap(context.Background(), time.Now()); err != nil {
+			r.log.Info(fmt.Sprintf("Can't clean up teardown logs: %s", err.Error()))
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reap deletes every teardown logs ConfigMap that was due for deletion before now
+func (r *teardownLogsReaper) reap(ctx context.Context, now time.Time) error {
+	var configMaps v1.ConfigMapList
+	if err := r.client.List(ctx, &configMaps, client.HasLabels{honkv1.TeardownLogsLabel}); err != nil {
+		return err
+	}
+
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		deleteAfter, err := time.Parse(time.RFC3339, cm.Annotations[honkv1.DeleteAfterAnnotation])
+		if err != nil || now.Before(deleteAfter) {
+			// Without a valid timestamp it's safer to keep the logs around
+			continue
+		}
+
+		if err := client.IgnoreNotFound(r.client.Delete(ctx, cm)); err != nil {
+			return err
+		}
+		r.log.Info(fmt.Sprintf("Deleted teardown logs ConfigMap %s/%s, kept until %s", cm.Namespace, cm.Name, deleteAfter))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	honkv1 "github.com/jeefy/kaas/api/v1"
+)
+
+var _ = Describe("Teardown logs", func() {
+	ctx := context.Background()
+	now := time.Now()
+
+	DescribeTable("reaping",
+		func(name string, labelled bool, deleteAfter string, wantDeleted bool) {
+			cm := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "default",
+					Labels:      map[string]string{"cluster": name},
+					Annotations: map[string]string{},
+				},
+			}
+			if labelled {
+				cm.Labels[honkv1.TeardownLogsLabel] = "true"
+			}
+			if deleteAfter != "" {
+				cm.Annotations[honkv1.DeleteAfterAnnotation] = deleteAfter
+			}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+			reaper := &teardownLogsReaper{client: k8sClient, log: logf.Log.WithName("teardown-logs")}
+			Expect(reaper.reap(ctx, now)).To(Succeed())
+
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, &v1.ConfigMap{})
+			if wantDeleted {
+				Expect(errors.IsNotFound(err)).To(BeTrue())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		},
+		Entry("expired", "reap-expired", true, now.Add(-time.Minute).UTC().Format(time.RFC3339), true),
+		Entry("not expired yet", "reap-fresh", true, now.Add(time.Hour).UTC().Format(time.RFC3339), false),
+		Entry("no timestamp", "reap-no-timestamp", true, "", false),
+		Entry("not teardown logs", "reap-unlabelled", false, now.Add(-time.Minute).UTC().Format(time.RFC3339), false),
+	)
+})